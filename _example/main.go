@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 )
 
@@ -23,7 +24,7 @@ var (
 	channelID    = "aa67cc66-23fd-476b-a9e3-70782de95457"
 	redirectURI  = "http://localhost:8080/callback"
 	authURL      = "http://localhost:8086/login"
-	tokenURL     = "http://localhost:8086/api/exchange"
+	tokenURL     = "http://localhost:8086/api/oauth/token"
 )
 
 func main() {
@@ -120,6 +121,13 @@ func (s *server) callback(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	User        User   `json:"user"`
+}
+
 type User struct {
 	ID          string `json:"id"`
 	Username    string `json:"username"`
@@ -143,11 +151,17 @@ type User struct {
 }
 
 func exchangeCode(code string) (*User, error) {
-	rq, err := http.NewRequest(http.MethodGet, tokenURL+"?code="+url.QueryEscape(code), nil)
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+
+	rq, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, err
 	}
 
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rq.SetBasicAuth(clientID, clientSecret)
 
 	rs, err := http.DefaultClient.Do(rq)
@@ -161,10 +175,10 @@ func exchangeCode(code string) (*User, error) {
 		return nil, errors.New("invalid response status: " + rs.Status)
 	}
 
-	var m User
-	if err = json.NewDecoder(rs.Body).Decode(&m); err != nil {
+	var t TokenResponse
+	if err = json.NewDecoder(rs.Body).Decode(&t); err != nil {
 		return nil, err
 	}
 
-	return &m, nil
+	return &t.User, nil
 }
@@ -0,0 +1,69 @@
+package xrand
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+const (
+	// Digits is the default alphabet of user-facing codes.
+	Digits = "0123456789"
+
+	charCode = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	// charCodeLength of 16 characters results in ~82 bits, enough for IDs and short-lived codes.
+	charCodeLength = 16
+	// secretLength of 50 characters results in ~258 bits.
+	secretLength = 50
+)
+
+//go:embed words.txt
+var wordList string
+
+// Words is the list RandWords picks from.
+var Words = strings.Fields(wordList)
+
+// RandString returns a random string of the given length using the characters of the alphabet.
+func RandString(length int, alphabet string) string {
+	chars := []rune(alphabet)
+	b := make([]rune, length)
+	for i := range b {
+		b[i] = chars[randIntN(len(chars))]
+	}
+	return string(b)
+}
+
+// RandWords returns the given number of random words separated by dashes.
+func RandWords(count int) string {
+	words := make([]string, count)
+	for i := range words {
+		words[i] = Words[randIntN(len(Words))]
+	}
+	return strings.Join(words, "-")
+}
+
+// RandCharCode returns a random alphanumeric code for IDs and short-lived codes.
+func RandCharCode() string {
+	return RandString(charCodeLength, charCode)
+}
+
+// RandSecret returns a random alphanumeric secret with at least 256 bits of entropy.
+func RandSecret() string {
+	return RandString(secretLength, charCode)
+}
+
+// randIntN returns a uniformly distributed random number in [0, n) read from crypto/rand.
+func randIntN(n int) int {
+	// numbers above the largest multiple of n are rejected to avoid modulo bias
+	limit := math.MaxUint32 - math.MaxUint32%uint32(n)
+	b := make([]byte, 4)
+	for {
+		_, _ = rand.Read(b)
+		if v := binary.BigEndian.Uint32(b); v < limit {
+			return int(v % uint32(n))
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package xtime
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"time"
 )
 
@@ -22,3 +24,21 @@ func (d *Duration) UnmarshalText(text []byte) error {
 func (d Duration) MarshalText() ([]byte, error) {
 	return []byte(time.Duration(d).String()), nil
 }
+
+// Scan reads a duration stored in the database as whole seconds.
+func (d *Duration) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*d = Duration(time.Duration(v) * time.Second)
+	case int32:
+		*d = Duration(time.Duration(v) * time.Second)
+	default:
+		return fmt.Errorf("cannot scan %T into xtime.Duration", src)
+	}
+	return nil
+}
+
+// Value stores the duration in the database as whole seconds.
+func (d Duration) Value() (driver.Value, error) {
+	return int64(time.Duration(d) / time.Second), nil
+}
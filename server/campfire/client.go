@@ -44,8 +44,22 @@ type Client struct {
 	token      TokenFunc
 }
 
+// AvailableRequests returns how many requests can be sent right now.
+func (c *Client) AvailableRequests() int {
+	return int(c.limiter.Tokens())
+}
+
+// AvailableBackgroundRequests returns how many requests background jobs can send right now.
+func (c *Client) AvailableBackgroundRequests() int {
+	reserved := min(c.cfg.Reserved, c.cfg.Burst-1)
+	return max(c.AvailableRequests()-reserved, 0)
+}
+
 func (c *Client) Do(ctx context.Context, token string, query string, vars map[string]any, rsBody any) error {
 	for range c.cfg.MaxRetries {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
 		if err := c.do(ctx, token, query, vars, rsBody); err != nil {
 			if errors.Is(err, ErrTooManyRequests) || errors.Is(err, ErrBadGateway) {
 				time.Sleep(time.Second)
@@ -0,0 +1,27 @@
+package campfire
+
+import (
+	"context"
+	_ "embed"
+)
+
+//go:embed queries/club_member.graphql
+var clubMemberQuery string
+
+// GetClubMember returns the membership of the user in the club.
+func (c *Client) GetClubMember(ctx context.Context, clubID string, userID string) (*ClubMember, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var member clubMemberResp
+	if err = c.Do(ctx, token, clubMemberQuery, map[string]any{
+		"clubId": clubID,
+		"userId": userID,
+	}, &member); err != nil {
+		return nil, err
+	}
+
+	return member.ClubMember, nil
+}
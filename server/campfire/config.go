@@ -7,15 +7,18 @@ import (
 )
 
 type Config struct {
-	Every      xtime.Duration `toml:"every"`
-	Burst      int            `toml:"burst"`
-	MaxRetries int            `toml:"max_retries"`
+	Every xtime.Duration `toml:"every"`
+	Burst int            `toml:"burst"`
+	// Reserved is the part of the burst kept for requests of users.
+	Reserved   int `toml:"reserved"`
+	MaxRetries int `toml:"max_retries"`
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf("\n Every: %s\n Burst: %d\n MaxRetries: %d",
+	return fmt.Sprintf("\n Every: %s\n Burst: %d\n Reserved: %d\n MaxRetries: %d",
 		c.Every,
 		c.Burst,
+		c.Reserved,
 		c.MaxRetries,
 	)
 }
@@ -3,10 +3,17 @@ package campfire
 import (
 	"context"
 	_ "embed"
+	"fmt"
+	"strconv"
+	"time"
 )
 
-//go:embed queries/message_history.graphql
-var historyQuery string
+var (
+	//go:embed queries/message_history.graphql
+	historyQuery string
+	//go:embed queries/send_message.graphql
+	sendMessageMutation string
+)
 
 func (c *Client) GetMessageHistory(ctx context.Context, channelID string) (*MessageHistory, error) {
 	token, err := c.token(ctx)
@@ -25,3 +32,37 @@ func (c *Client) GetMessageHistory(ctx context.Context, channelID string) (*Mess
 
 	return &history.MessagesFromHistoryV2, nil
 }
+
+// SendMessage sends a message to the channel, the mentioned users are notified about it.
+func (c *Client) SendMessage(ctx context.Context, channelID string, content string, mentionedUserIDs []string) (*Message, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var message sendMessageResp
+	if err = c.Do(ctx, token, sendMessageMutation, map[string]any{
+		"input": map[string]any{
+			"channelId":        channelID,
+			"content":          content,
+			"mentionedUserIds": mentionedUserIDs,
+		},
+	}, &message); err != nil {
+		return nil, err
+	}
+
+	return &message.SendMessage.Message, nil
+}
+
+// SentAtTime parses SentAt which is either an RFC 3339 timestamp or unix milliseconds.
+func (m Message) SentAtTime() (time.Time, error) {
+	if sentAt, err := time.Parse(time.RFC3339Nano, m.SentAt); err == nil {
+		return sentAt, nil
+	}
+
+	ms, err := strconv.ParseInt(m.SentAt, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid sentAt %q", m.SentAt)
+	}
+	return time.UnixMilli(ms), nil
+}
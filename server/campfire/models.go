@@ -2,6 +2,7 @@ package campfire
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -41,16 +42,28 @@ type historyResp struct {
 }
 
 type MessageHistory struct {
-	Messages []struct {
-		Message struct {
-			Id     string `json:"id"`
-			Sender struct {
-				User User `json:"user"`
-			} `json:"sender"`
-			SentAt  string `json:"sentAt"`
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"messages"`
+	Messages []HistoryMessage `json:"messages"`
+}
+
+type HistoryMessage struct {
+	Message Message `json:"message"`
+}
+
+type Message struct {
+	Id      string        `json:"id"`
+	Sender  MessageSender `json:"sender"`
+	SentAt  string        `json:"sentAt"`
+	Content string        `json:"content"`
+}
+
+type sendMessageResp struct {
+	SendMessage struct {
+		Message Message `json:"message"`
+	} `json:"sendMessage"`
+}
+
+type MessageSender struct {
+	User User `json:"user"`
 }
 
 type User struct {
@@ -86,3 +99,29 @@ type usersResp struct {
 type userByIDResp struct {
 	User User `json:"userById"`
 }
+
+type clubMemberResp struct {
+	ClubMember *ClubMember `json:"clubMember"`
+}
+
+type ClubMember struct {
+	Role     ClubRole `json:"role"`
+	JoinedAt string   `json:"joinedAt"`
+}
+
+type ClubRole string
+
+const (
+	ClubRoleMember    ClubRole = "MEMBER"
+	ClubRoleModerator ClubRole = "MODERATOR"
+	ClubRoleAdmin     ClubRole = "ADMIN"
+	ClubRoleOwner     ClubRole = "OWNER"
+)
+
+// ClubRoles are all club roles ordered from lowest to highest.
+var ClubRoles = []ClubRole{ClubRoleMember, ClubRoleModerator, ClubRoleAdmin, ClubRoleOwner}
+
+// AtLeast reports whether the role is the same as or higher than the given role.
+func (r ClubRole) AtLeast(role ClubRole) bool {
+	return max(slices.Index(ClubRoles, r), 0) >= max(slices.Index(ClubRoles, role), 0)
+}
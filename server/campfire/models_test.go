@@ -0,0 +1,31 @@
+package campfire
+
+import "testing"
+
+func TestClubRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		name string
+		role ClubRole
+		min  ClubRole
+		want bool
+	}{
+		{name: "member at least member", role: ClubRoleMember, min: ClubRoleMember, want: true},
+		{name: "member at least moderator", role: ClubRoleMember, min: ClubRoleModerator, want: false},
+		{name: "moderator at least member", role: ClubRoleModerator, min: ClubRoleMember, want: true},
+		{name: "admin at least moderator", role: ClubRoleAdmin, min: ClubRoleModerator, want: true},
+		{name: "admin at least owner", role: ClubRoleAdmin, min: ClubRoleOwner, want: false},
+		{name: "owner at least admin", role: ClubRoleOwner, min: ClubRoleAdmin, want: true},
+		{name: "owner at least owner", role: ClubRoleOwner, min: ClubRoleOwner, want: true},
+		{name: "unknown role ranks as member", role: "GUEST", min: ClubRoleMember, want: true},
+		{name: "unknown role below moderator", role: "GUEST", min: ClubRoleModerator, want: false},
+		{name: "empty role ranks as member", role: "", min: ClubRoleModerator, want: false},
+		{name: "unknown minimum role ranks as member", role: ClubRoleMember, min: "GUEST", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.role.AtLeast(tt.min); got != tt.want {
+				t.Errorf("AtLeast() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// readableChannelTTL is how long a channel stays known as readable before it is checked again.
+	readableChannelTTL = 10 * time.Minute
+	// unreadableChannelTTL is how long a failed check is remembered.
+	unreadableChannelTTL = 1 * time.Minute
+)
+
+type readableChannel struct {
+	checkedAt time.Time
+	err       error
+}
+
+func (c readableChannel) expired(now time.Time) bool {
+	if c.err != nil {
+		return now.Sub(c.checkedAt) >= unreadableChannelTTL
+	}
+	return now.Sub(c.checkedAt) >= readableChannelTTL
+}
+
+// CheckChannelReadable checks whether the Campfire token can read the message history of the channel.
+func (s *Server) CheckChannelReadable(ctx context.Context, channelID string) error {
+	s.readableChannelsMu.Lock()
+	channel, ok := s.readableChannels[channelID]
+	s.readableChannelsMu.Unlock()
+	if ok && !channel.expired(time.Now()) {
+		return channel.err
+	}
+
+	_, err := s.Campfire.GetMessageHistory(ctx, channelID)
+	if err != nil {
+		err = fmt.Errorf("channel can't be read: %w", err)
+		// a canceled request says nothing about the channel
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+	}
+
+	s.readableChannelsMu.Lock()
+	s.readableChannels[channelID] = readableChannel{
+		checkedAt: time.Now(),
+		err:       err,
+	}
+	s.readableChannelsMu.Unlock()
+	return err
+}
+
+// cleanupReadableChannels removes the expired readability checks.
+func (s *Server) cleanupReadableChannels() {
+	s.readableChannelsMu.Lock()
+	defer s.readableChannelsMu.Unlock()
+
+	now := time.Now()
+	for channelID, channel := range s.readableChannels {
+		if channel.expired(now) {
+			delete(s.readableChannels, channelID)
+		}
+	}
+}
@@ -3,84 +3,152 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/topi314/campfire-auth/server/campfire"
 	"github.com/topi314/campfire-auth/server/database"
 )
 
-func (s *Server) loginCodeChecker() {
-	for {
-		s.doLoginCodeCheck()
-		time.Sleep(1 * time.Second)
-	}
+// loginCodeClockSkew tolerates the clocks of Campfire and the database being slightly apart.
+const loginCodeClockSkew = 10 * time.Second
+
+// codeMatcher matches the code of a login in a message as a whole token.
+type codeMatcher struct {
+	regex    *regexp.Regexp
+	prefixed bool
 }
 
-func (s *Server) doLoginCodeCheck() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func newCodeMatcher(code string, prefix string) codeMatcher {
+	var pattern strings.Builder
+	pattern.WriteString(`(?i)`)
+	if prefix != "" {
+		pattern.WriteString(regexp.QuoteMeta(prefix))
+		pattern.WriteString(`\s+`)
+	}
 
-	logins, err := s.DB.GetNextLogins(ctx)
-	if err != nil {
-		slog.ErrorContext(ctx, "Failed to get next login", slog.String("err", err.Error()))
-		return
+	if words := strings.Split(code, "-"); len(words) > 1 {
+		for i, word := range words {
+			if i > 0 {
+				pattern.WriteString(`[ -]`)
+			}
+			pattern.WriteString(regexp.QuoteMeta(word))
+		}
+	} else {
+		runes := []rune(code)
+		half := len(runes) / 2
+		pattern.WriteString(`(?:`)
+		pattern.WriteString(regexp.QuoteMeta(code))
+		if half > 0 {
+			pattern.WriteString(`|`)
+			pattern.WriteString(regexp.QuoteMeta(string(runes[:half])))
+			pattern.WriteString(`[ -]`)
+			pattern.WriteString(regexp.QuoteMeta(string(runes[half:])))
+		}
+		pattern.WriteString(`)`)
 	}
-	logins = filterLogins(logins)
-	if len(logins) == 0 {
-		time.Sleep(1 * time.Second)
-		return
+
+	return codeMatcher{
+		regex:    regexp.MustCompile(pattern.String()),
+		prefixed: prefix != "",
 	}
+}
 
-	if err = s.handleLoginCheck(ctx, logins); err != nil {
-		ids := make([]int, 0, len(logins))
-		for _, login := range logins {
-			ids = append(ids, login.ID)
+// Match reports whether the message content contains the code.
+func (m codeMatcher) Match(content string) bool {
+	for offset := 0; offset < len(content); {
+		loc := m.regex.FindStringIndex(content[offset:])
+		if loc == nil {
+			return false
 		}
-		if err = s.DB.UpdateLoginsLastUpdatedAt(ctx, ids); err != nil {
-			slog.ErrorContext(ctx, "Failed to update login last updated at", slog.String("err", err.Error()))
+		start, end := offset+loc[0], offset+loc[1]
+		if isCodeStart(content[:start], !m.prefixed) && isCodeEnd(content[end:]) {
+			return true
 		}
-		return
+		_, size := utf8.DecodeRuneInString(content[start:])
+		offset = start + max(size, 1)
 	}
+	return false
 }
 
-func filterLogins(logins []database.Login) []database.Login {
-	if len(logins) == 0 {
-		return nil
+// isCodeStart reports whether a code can start after the given text.
+func isCodeStart(before string, checkDigits bool) bool {
+	r, size := utf8.DecodeLastRuneInString(before)
+	if size == 0 {
+		return true
+	}
+	if !isCodeSeparator(r) {
+		return false
 	}
+	prev, _ := utf8.DecodeLastRuneInString(before[:len(before)-size])
+	return !checkDigits || !unicode.IsSpace(r) || !unicode.IsDigit(prev)
+}
 
-	first := logins[0]
-	sameChannelLogins := make([]database.Login, 0, len(logins))
-	for _, login := range logins {
-		if login.ChannelID == first.ChannelID {
-			sameChannelLogins = append(sameChannelLogins, login)
-		}
+// isCodeEnd reports whether a code can end before the given text.
+func isCodeEnd(after string) bool {
+	r, size := utf8.DecodeRuneInString(after)
+	if size == 0 {
+		return true
+	}
+	if !isCodeSeparator(r) {
+		return false
 	}
+	next, _ := utf8.DecodeRuneInString(after[size:])
+	return !unicode.IsSpace(r) || !unicode.IsDigit(next)
+}
 
-	return sameChannelLogins
+func isCodeSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-'
+}
+
+// ErrClubMemberNotAllowed is returned when a user lacks the club membership the client requires.
+var ErrClubMemberNotAllowed = errors.New("the user does not have the club membership required by the client")
+
+type loginUser struct {
+	User       campfire.User
+	ClubMember *campfire.ClubMember
+	MessageID  string
 }
 
 func (s *Server) handleLoginCheck(ctx context.Context, logins []database.Login) error {
-	members, err := s.checkForCode(ctx, logins)
+	users, err := s.checkForCode(ctx, logins)
 	if err != nil {
 		return err
 	}
 
-	updates := make(map[int]json.RawMessage, len(logins))
-	for id, member := range members {
-		memberData, err := json.Marshal(member)
+	updates := make(map[int]database.LoginUser, len(users))
+	for id, user := range users {
+		userData, err := json.Marshal(user.User)
 		if err != nil {
 			return err
 		}
 
-		updates[id] = memberData
+		update := database.LoginUser{
+			User:      userData,
+			MessageID: user.MessageID,
+			SenderID:  user.User.ID,
+		}
+		if user.ClubMember != nil {
+			memberData, err := json.Marshal(user.ClubMember)
+			if err != nil {
+				return err
+			}
+			rawMemberData := json.RawMessage(memberData)
+			update.ClubMember = &rawMemberData
+		}
+		updates[id] = update
 	}
 
-	return s.DB.UpdateLoginUsers(ctx, updates)
+	return s.DB.UpdateLoginUsers(ctx, updates, s.Cfg.Login.Lifetimes())
 }
 
-func (s *Server) checkForCode(ctx context.Context, logins []database.Login) (map[int]campfire.User, error) {
+func (s *Server) checkForCode(ctx context.Context, logins []database.Login) (map[int]loginUser, error) {
 	client := logins[0]
 
 	history, err := s.Campfire.GetMessageHistory(ctx, client.ChannelID)
@@ -89,15 +157,128 @@ func (s *Server) checkForCode(ctx context.Context, logins []database.Login) (map
 		return nil, err
 	}
 
-	users := make(map[int]campfire.User)
+	messageIDs := make([]string, 0, len(history.Messages))
+	for _, message := range history.Messages {
+		messageIDs = append(messageIDs, message.Message.Id)
+	}
+	usedMessageIDs, err := s.DB.GetUsedLoginMessageIDs(ctx, messageIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get used login message ids", slog.String("err", err.Error()))
+		return nil, err
+	}
+	// each message can only verify a single login
+	usedMessages := make(map[string]struct{}, len(usedMessageIDs))
+	for _, id := range usedMessageIDs {
+		usedMessages[id] = struct{}{}
+	}
+
+	sentAts := make([]time.Time, len(history.Messages))
+	for i, message := range history.Messages {
+		sentAt, err := message.Message.SentAtTime()
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to parse message sent at", slog.String("message_id", message.Message.Id), slog.String("err", err.Error()))
+			continue
+		}
+		sentAts[i] = sentAt
+	}
+
+	clients := make(map[string]*database.Client)
+	members := make(map[string]loginClubMember)
+	users := make(map[int]loginUser)
 	for _, login := range logins {
-		for _, message := range history.Messages {
-			if strings.Contains(message.Message.Content, login.Code) {
-				users[login.ID] = message.Message.Sender.User
-				break
+		codeMatcher := newCodeMatcher(login.Code, s.Cfg.Login.CodePrefix)
+
+		loginClient, ok := clients[login.ClientID]
+		if !ok {
+			if loginClient, err = s.DB.GetClient(ctx, login.ClientID); err != nil {
+				slog.ErrorContext(ctx, "Failed to get login client", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+				return nil, err
 			}
+			clients[login.ClientID] = loginClient
+		}
+
+		for i, message := range history.Messages {
+			// the code can only be posted after the login was created
+			if sentAts[i].Before(login.CreatedAt.Add(-loginCodeClockSkew)) {
+				continue
+			}
+			if _, ok := usedMessages[message.Message.Id]; ok {
+				continue
+			}
+			if !codeMatcher.Match(message.Message.Content) {
+				continue
+			}
+
+			sender := message.Message.Sender.User
+			// the allowed membership depends on the client
+			memberKey := login.ClientID + "/" + login.ClubID + "/" + sender.ID
+			member, ok := members[memberKey]
+			if !ok {
+				member.member, member.err = s.GetLoginClubMember(ctx, *loginClient, login.ClubID, sender.ID)
+				members[memberKey] = member
+			}
+			if errors.Is(member.err, ErrClubMemberNotAllowed) {
+				slog.InfoContext(ctx, "Ignoring login code from user without the required club membership",
+					slog.Int("login_id", login.ID),
+					slog.String("club_id", login.ClubID),
+					slog.String("user_id", sender.ID),
+				)
+				continue
+			}
+			if member.err != nil {
+				slog.ErrorContext(ctx, "Failed to get login club member", slog.String("club_id", login.ClubID), slog.String("user_id", sender.ID), slog.String("err", member.err.Error()))
+				return nil, member.err
+			}
+
+			usedMessages[message.Message.Id] = struct{}{}
+			users[login.ID] = loginUser{
+				User:       sender,
+				ClubMember: member.member,
+				MessageID:  message.Message.Id,
+			}
+			break
 		}
 	}
 
 	return users, nil
 }
+
+type loginClubMember struct {
+	member *campfire.ClubMember
+	err    error
+}
+
+// GetLoginClubMember returns the club membership of a user verifying a login of the client.
+func (s *Server) GetLoginClubMember(ctx context.Context, client database.Client, clubID string, userID string) (*campfire.ClubMember, error) {
+	member, err := s.Campfire.GetClubMember(ctx, clubID, userID)
+	if err != nil {
+		if RequiresClubMember(client) {
+			return nil, fmt.Errorf("failed to get club member: %w", err)
+		}
+		slog.ErrorContext(ctx, "Failed to get club member", slog.String("club_id", clubID), slog.String("user_id", userID), slog.String("err", err.Error()))
+		return nil, nil
+	}
+	if !IsAllowedClubMember(client, member) {
+		return nil, ErrClubMemberNotAllowed
+	}
+	return member, nil
+}
+
+// RequiresClubMember reports whether logins of the client need to be verified by a member of the club.
+func RequiresClubMember(client database.Client) bool {
+	return client.RequireClubMember || client.MinClubRole != nil
+}
+
+// IsAllowedClubMember checks the club membership of a user against the requirements of the client.
+func IsAllowedClubMember(client database.Client, member *campfire.ClubMember) bool {
+	if !RequiresClubMember(client) {
+		return true
+	}
+	if member == nil {
+		return false
+	}
+	if client.MinClubRole != nil {
+		return member.Role.AtLeast(campfire.ClubRole(*client.MinClubRole))
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package server
+
+import "testing"
+
+func TestCodeMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		prefix  string
+		content string
+		want    bool
+	}{
+		{name: "whole message", code: "123456", content: "123456", want: true},
+		{name: "inside a sentence", code: "123456", content: "my code is 123456.", want: true},
+		{name: "split in half with space", code: "123456", content: "123 456", want: true},
+		{name: "split in half with dash", code: "123456", content: "123-456", want: true},
+		{name: "odd length split", code: "1234567", content: "123 4567", want: true},
+		{name: "non canonical split", code: "123456", content: "12 34 56", want: false},
+		{name: "split in the wrong place", code: "123456", content: "12 3456", want: false},
+		{name: "other code", code: "123456", content: "654321", want: false},
+		{name: "longer number", code: "123456", content: "1234567", want: false},
+		{name: "inside a word", code: "123456", content: "abc123456", want: false},
+		{name: "phone number", code: "123456", content: "+49 123 456 789", want: false},
+		{name: "phone number without country code", code: "123456", content: "call 123 456 789", want: false},
+		{name: "phone number with dashes", code: "123456", content: "555-123-456", want: false},
+		{name: "preceded by digit group", code: "123456", content: "0171 123456", want: false},
+		{name: "followed by digit group", code: "123456", content: "123456 789", want: false},
+		{name: "partial overlap", code: "123456", content: "1123456 654321", want: false},
+		{name: "match after an invalid one", code: "123456", content: "not 1123456 but 123456", want: true},
+		{name: "case insensitive", code: "AB12CD", content: "ab12cd", want: true},
+		{name: "word code", code: "maple-otter-prism", content: "maple-otter-prism", want: true},
+		{name: "word code with spaces", code: "maple-otter-prism", content: "Maple Otter Prism", want: true},
+		{name: "word code without separators", code: "maple-otter-prism", content: "mapleotterprism", want: false},
+		{name: "word code missing a word", code: "maple-otter-prism", content: "maple-otter", want: false},
+		{name: "word code inside longer code", code: "maple-otter", content: "maple-otter-prism", want: false},
+		{name: "prefix", code: "123456", prefix: "!auth", content: "!auth 123456", want: true},
+		{name: "prefix with split code", code: "123456", prefix: "!auth", content: "!auth 123 456", want: true},
+		{name: "prefix case insensitive", code: "123456", prefix: "!auth", content: "!AUTH 123456", want: true},
+		{name: "prefix with multiple spaces", code: "123456", prefix: "!auth", content: "!auth   123456", want: true},
+		{name: "prefix after digits", code: "123456", prefix: "!auth", content: "2 !auth 123456", want: true},
+		{name: "missing prefix", code: "123456", prefix: "!auth", content: "123456", want: false},
+		{name: "prefix without space", code: "123456", prefix: "!auth", content: "!auth123456", want: false},
+		{name: "prefix inside a word", code: "123456", prefix: "auth", content: "oauth 123456", want: false},
+		{name: "prefix followed by digit group", code: "123456", prefix: "!auth", content: "!auth 123456 789", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCodeMatcher(tt.code, tt.prefix).Match(tt.content); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
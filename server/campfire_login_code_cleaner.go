@@ -17,9 +17,14 @@ func (s *Server) doLoginCodeClean() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := s.DB.DeleteExpiredLogins(ctx); err != nil {
+	if err := s.DB.DeleteExpiredLogins(ctx, time.Duration(s.Cfg.Login.CodeLifetime)); err != nil {
 		slog.ErrorContext(ctx, "Failed to delete expired logins", slog.String("err", err.Error()))
 		return
 	}
 
+	if err := s.DB.DeleteExpiredLoginMessages(ctx, time.Duration(s.Cfg.Login.CodeLifetime)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired login messages", slog.String("err", err.Error()))
+		return
+	}
+
 }
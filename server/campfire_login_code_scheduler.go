@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	// loginCodeSchedulerTick is how often the scheduler looks for channels which are due to be polled.
+	loginCodeSchedulerTick = 500 * time.Millisecond
+
+	// channels are polled more often while their newest pending login is still fresh.
+	loginCodeFreshPollInterval  = 1 * time.Second
+	loginCodeRecentPollInterval = 3 * time.Second
+	loginCodeStalePollInterval  = 5 * time.Second
+	loginCodeFreshAge           = 1 * time.Minute
+	loginCodeRecentAge          = 3 * time.Minute
+)
+
+type loginChannelState struct {
+	lastPolledAt time.Time
+	nextPollAt   time.Time
+	polling      bool
+}
+
+// loginCodeScheduler decides which channels with pending logins are polled next.
+type loginCodeScheduler struct {
+	mu       sync.Mutex
+	channels map[string]*loginChannelState
+}
+
+func (s *Server) loginCodeChecker() {
+	scheduler := &loginCodeScheduler{
+		channels: make(map[string]*loginChannelState),
+	}
+	for {
+		s.scheduleLoginCodeChecks(scheduler)
+		time.Sleep(loginCodeSchedulerTick)
+	}
+}
+
+func (s *Server) scheduleLoginCodeChecks(scheduler *loginCodeScheduler) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logins, err := s.DB.GetNextLogins(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get next logins", slog.String("err", err.Error()))
+		return
+	}
+
+	channelLogins := make(map[string][]database.Login)
+	for _, login := range logins {
+		channelLogins[login.ChannelID] = append(channelLogins[login.ChannelID], login)
+	}
+
+	for _, channelID := range scheduler.dueChannels(channelLogins, s.Campfire.AvailableBackgroundRequests(), time.Now()) {
+		go s.pollLoginChannel(scheduler, channelLogins[channelID])
+	}
+}
+
+// dueChannels returns the channels to poll now and marks them as polling.
+func (sc *loginCodeScheduler) dueChannels(channelLogins map[string][]database.Login, limit int, now time.Time) []string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for channelID, state := range sc.channels {
+		if _, ok := channelLogins[channelID]; !ok && !state.polling {
+			delete(sc.channels, channelID)
+		}
+	}
+
+	var due []string
+	for channelID := range channelLogins {
+		state, ok := sc.channels[channelID]
+		if !ok {
+			state = &loginChannelState{}
+			sc.channels[channelID] = state
+		}
+		if !state.polling && !now.Before(state.nextPollAt) {
+			due = append(due, channelID)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b string) int {
+		return sc.channels[a].lastPolledAt.Compare(sc.channels[b].lastPolledAt)
+	})
+	// channels which don't fit in the budget are polled first on the next tick
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, channelID := range due {
+		sc.channels[channelID].polling = true
+	}
+
+	return due
+}
+
+func (sc *loginCodeScheduler) polled(channelID string, interval time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	state, ok := sc.channels[channelID]
+	if !ok {
+		return
+	}
+	state.polling = false
+	state.lastPolledAt = time.Now()
+	state.nextPollAt = state.lastPolledAt.Add(interval)
+}
+
+func (s *Server) pollLoginChannel(scheduler *loginCodeScheduler, logins []database.Login) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	channelID := logins[0].ChannelID
+	defer scheduler.polled(channelID, loginPollInterval(logins, time.Now()))
+
+	if err := s.handleLoginCheck(ctx, logins); err != nil {
+		slog.ErrorContext(ctx, "Failed to check logins", slog.String("channel_id", channelID), slog.String("err", err.Error()))
+	}
+
+	ids := make([]int, 0, len(logins))
+	for _, login := range logins {
+		ids = append(ids, login.ID)
+	}
+	if err := s.DB.UpdateLoginsLastUpdatedAt(ctx, ids); err != nil {
+		slog.ErrorContext(ctx, "Failed to update login last updated at", slog.String("err", err.Error()))
+	}
+}
+
+// loginPollInterval returns how long to wait before polling the channel of the logins again.
+func loginPollInterval(logins []database.Login, now time.Time) time.Duration {
+	var newest time.Time
+	for _, login := range logins {
+		if login.CreatedAt.After(newest) {
+			newest = login.CreatedAt
+		}
+	}
+
+	switch age := now.Sub(newest); {
+	case age < loginCodeFreshAge:
+		return loginCodeFreshPollInterval
+	case age < loginCodeRecentAge:
+		return loginCodeRecentPollInterval
+	default:
+		return loginCodeStalePollInterval
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+func TestLoginPollInterval(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		createdAts []time.Duration
+		want       time.Duration
+	}{
+		{name: "fresh login", createdAts: []time.Duration{10 * time.Second}, want: loginCodeFreshPollInterval},
+		{name: "recent login", createdAts: []time.Duration{2 * time.Minute}, want: loginCodeRecentPollInterval},
+		{name: "stale login", createdAts: []time.Duration{4 * time.Minute}, want: loginCodeStalePollInterval},
+		{name: "newest login decides", createdAts: []time.Duration{4 * time.Minute, 10 * time.Second, 2 * time.Minute}, want: loginCodeFreshPollInterval},
+		{name: "login created in the future", createdAts: []time.Duration{-5 * time.Second}, want: loginCodeFreshPollInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := make([]database.Login, 0, len(tt.createdAts))
+			for _, age := range tt.createdAts {
+				logins = append(logins, database.Login{CreatedAt: now.Add(-age)})
+			}
+			if got := loginPollInterval(logins, now); got != tt.want {
+				t.Errorf("loginPollInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueChannels(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		channels map[string]*loginChannelState
+		logins   []string
+		limit    int
+		want     []string
+	}{
+		{
+			name:   "new channels",
+			logins: []string{"a", "b"},
+			limit:  10,
+			want:   []string{"a", "b"},
+		},
+		{
+			name: "least recently polled first",
+			channels: map[string]*loginChannelState{
+				"a": {lastPolledAt: now.Add(-2 * time.Second), nextPollAt: now},
+				"b": {lastPolledAt: now.Add(-5 * time.Second), nextPollAt: now},
+				"c": {lastPolledAt: now.Add(-3 * time.Second), nextPollAt: now},
+			},
+			logins: []string{"a", "b", "c"},
+			limit:  2,
+			want:   []string{"b", "c"},
+		},
+		{
+			name: "channel not due yet",
+			channels: map[string]*loginChannelState{
+				"a": {lastPolledAt: now, nextPollAt: now.Add(time.Second)},
+			},
+			logins: []string{"a", "b"},
+			limit:  10,
+			want:   []string{"b"},
+		},
+		{
+			name: "channel still polling",
+			channels: map[string]*loginChannelState{
+				"a": {polling: true},
+			},
+			logins: []string{"a"},
+			limit:  10,
+			want:   nil,
+		},
+		{
+			name:   "no budget left",
+			logins: []string{"a", "b"},
+			limit:  0,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := &loginCodeScheduler{channels: tt.channels}
+			if scheduler.channels == nil {
+				scheduler.channels = make(map[string]*loginChannelState)
+			}
+			channelLogins := make(map[string][]database.Login, len(tt.logins))
+			for _, channelID := range tt.logins {
+				channelLogins[channelID] = []database.Login{{ChannelID: channelID}}
+			}
+
+			got := scheduler.dueChannels(channelLogins, tt.limit, now)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("dueChannels() = %v, want %v", got, tt.want)
+			}
+			for _, channelID := range got {
+				if !scheduler.channels[channelID].polling {
+					t.Errorf("channel %s is not marked as polling", channelID)
+				}
+			}
+		})
+	}
+}
+
+func TestDueChannelsRemovesChannelsWithoutLogins(t *testing.T) {
+	scheduler := &loginCodeScheduler{
+		channels: map[string]*loginChannelState{
+			"a": {},
+			"b": {polling: true},
+		},
+	}
+
+	scheduler.dueChannels(map[string][]database.Login{}, 10, time.Now())
+
+	if _, ok := scheduler.channels["a"]; ok {
+		t.Error("channel a without logins was not removed")
+	}
+	if _, ok := scheduler.channels["b"]; !ok {
+		t.Error("channel b which is still polling was removed")
+	}
+}
@@ -14,6 +14,7 @@ func (s *Server) cleanup() {
 	for {
 		s.doNotifyExpiringCampfireTokens()
 		s.doCleanupCampfireTokens()
+		s.cleanupReadableChannels()
 		time.Sleep(5 * time.Minute)
 	}
 }
@@ -1,14 +1,19 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/BurntSushi/toml"
 
+	"github.com/topi314/campfire-auth/internal/xjwt"
+	"github.com/topi314/campfire-auth/internal/xrand"
 	"github.com/topi314/campfire-auth/internal/xtime"
 	"github.com/topi314/campfire-auth/server/campfire"
 	"github.com/topi314/campfire-auth/server/database"
@@ -28,6 +33,16 @@ func LoadConfig(cfgPath string) (Config, error) {
 		return Config{}, fmt.Errorf("failed to decode config file: %w", err)
 	}
 
+	if err = cfg.Login.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid login config: %w", err)
+	}
+	if cfg.Session.Enabled && cfg.Session.Secret == "" {
+		return Config{}, errors.New("invalid session config: secret is required")
+	}
+	if cfg.Account.ClientID != "" && !cfg.Session.Enabled {
+		return Config{}, errors.New("invalid account config: sessions have to be enabled")
+	}
+
 	return cfg, nil
 }
 
@@ -44,8 +59,30 @@ func defaultConfig() Config {
 		Campfire: campfire.Config{
 			Every:      xtime.Duration(1 * time.Second),
 			Burst:      40,
+			Reserved:   10,
 			MaxRetries: 3,
 		},
+		Login: LoginConfig{
+			CodeLifetime:     xtime.Duration(240 * time.Second),
+			VerifiedLifetime: xtime.Duration(120 * time.Second),
+			ExchangeLifetime: xtime.Duration(60 * time.Second),
+			CodeLength:       6,
+			CodeAlphabet:     xrand.Digits,
+		},
+		Session: SessionConfig{
+			Lifetime: xtime.Duration(7 * 24 * time.Hour),
+		},
+		OAuth: OAuthConfig{
+			AccessTokenLifetime:        xtime.Duration(1 * time.Hour),
+			RefreshTokenLifetime:       xtime.Duration(30 * 24 * time.Hour),
+			ClientTokenLifetime:        xtime.Duration(15 * time.Minute),
+			InitialAccessTokenLifetime: xtime.Duration(7 * 24 * time.Hour),
+		},
+		OIDC: OIDCConfig{
+			SigningAlgorithm:    xjwt.AlgorithmRS256,
+			KeyRotationInterval: xtime.Duration(30 * 24 * time.Hour),
+			IDTokenLifetime:     xtime.Duration(1 * time.Hour),
+		},
 	}
 }
 
@@ -56,16 +93,26 @@ type Config struct {
 	Database      database.Config     `toml:"database"`
 	Campfire      campfire.Config     `toml:"campfire"`
 	Notifications NotificationsConfig `toml:"notifications"`
+	Login         LoginConfig         `toml:"login"`
+	Session       SessionConfig       `toml:"session"`
+	Account       AccountConfig       `toml:"account"`
+	OAuth         OAuthConfig         `toml:"oauth"`
+	OIDC          OIDCConfig          `toml:"oidc"`
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf("Dev: %t\nLog: %s\nServer: %s\nDatabase: %s\nCampfire: %s\nNotifications: %s",
+	return fmt.Sprintf("Dev: %t\nLog: %s\nServer: %s\nDatabase: %s\nCampfire: %s\nNotifications: %s\nLogin: %s\nSession: %s\nAccount: %s\nOAuth: %s\nOIDC: %s",
 		c.Dev,
 		c.Log,
 		c.Server,
 		c.Database,
 		c.Campfire,
 		c.Notifications,
+		c.Login,
+		c.Session,
+		c.Account,
+		c.OAuth,
+		c.OIDC,
 	)
 }
 
@@ -115,3 +162,131 @@ func (c NotificationsConfig) String() string {
 		c.WebhookURL,
 	)
 }
+
+type LoginConfig struct {
+	CodeLifetime     xtime.Duration `toml:"code_lifetime"`
+	VerifiedLifetime xtime.Duration `toml:"verified_lifetime"`
+	ExchangeLifetime xtime.Duration `toml:"exchange_lifetime"`
+	CodePrefix       string         `toml:"code_prefix"`
+	CodeLength       int            `toml:"code_length"`
+	CodeAlphabet     string         `toml:"code_alphabet"`
+	CodeWords        bool           `toml:"code_words"`
+}
+
+func (c LoginConfig) String() string {
+	return fmt.Sprintf("\n CodeLifetime: %s\n VerifiedLifetime: %s\n ExchangeLifetime: %s\n CodePrefix: %s\n CodeLength: %d\n CodeAlphabet: %s\n CodeWords: %t",
+		c.CodeLifetime,
+		c.VerifiedLifetime,
+		c.ExchangeLifetime,
+		c.CodePrefix,
+		c.CodeLength,
+		c.CodeAlphabet,
+		c.CodeWords,
+	)
+}
+
+// minCodeCombinations is the minimum number of different codes, as many as six digits have.
+const minCodeCombinations = 1_000_000
+
+func (c LoginConfig) Validate() error {
+	if c.CodeLength < 1 {
+		return errors.New("code_length must be at least 1")
+	}
+
+	size := len(xrand.Words)
+	if !c.CodeWords {
+		chars := []rune(c.CodeAlphabet)
+		if len(chars) < 2 {
+			return errors.New("code_alphabet must contain at least 2 characters")
+		}
+		if strings.ContainsAny(c.CodeAlphabet, " -/?#%") {
+			return errors.New("code_alphabet must not contain spaces, dashes or url characters")
+		}
+		// codes are matched case-insensitively
+		seen := make(map[rune]struct{}, len(chars))
+		for _, char := range chars {
+			char = unicode.ToLower(char)
+			if _, ok := seen[char]; ok {
+				return fmt.Errorf("code_alphabet must not contain a character twice, ignoring case: %q", char)
+			}
+			seen[char] = struct{}{}
+		}
+		size = len(chars)
+	}
+
+	if math.Pow(float64(size), float64(c.CodeLength)) < minCodeCombinations {
+		return fmt.Errorf("code_length is too short, codes need at least %d combinations", minCodeCombinations)
+	}
+	return nil
+}
+
+// NewCode generates a new code the user has to post.
+func (c LoginConfig) NewCode() string {
+	if c.CodeWords {
+		return xrand.RandWords(c.CodeLength)
+	}
+	return xrand.RandString(c.CodeLength, c.CodeAlphabet)
+}
+
+// Lifetimes returns the default login lifetimes, clients can override them.
+func (c LoginConfig) Lifetimes() database.LoginLifetimes {
+	return database.LoginLifetimes{
+		Code:     time.Duration(c.CodeLifetime),
+		Verified: time.Duration(c.VerifiedLifetime),
+		Exchange: time.Duration(c.ExchangeLifetime),
+	}
+}
+
+// SessionConfig configures the browser session which lets returning users skip posting a code.
+type SessionConfig struct {
+	Enabled  bool           `toml:"enabled"`
+	Lifetime xtime.Duration `toml:"lifetime"`
+	Secret   string         `toml:"secret"`
+}
+
+func (c SessionConfig) String() string {
+	return fmt.Sprintf("\n Enabled: %t\n Lifetime: %s\n Secret: %s",
+		c.Enabled,
+		c.Lifetime,
+		strings.Repeat("*", len(c.Secret)),
+	)
+}
+
+// AccountConfig configures the account page.
+type AccountConfig struct {
+	ClientID string `toml:"client_id"`
+}
+
+func (c AccountConfig) String() string {
+	return fmt.Sprintf("\n ClientID: %s", c.ClientID)
+}
+
+type OAuthConfig struct {
+	AccessTokenLifetime        xtime.Duration `toml:"access_token_lifetime"`
+	RefreshTokenLifetime       xtime.Duration `toml:"refresh_token_lifetime"`
+	ClientTokenLifetime        xtime.Duration `toml:"client_token_lifetime"`
+	InitialAccessTokenLifetime xtime.Duration `toml:"initial_access_token_lifetime"`
+}
+
+func (c OAuthConfig) String() string {
+	return fmt.Sprintf("\n AccessTokenLifetime: %s\n RefreshTokenLifetime: %s\n ClientTokenLifetime: %s\n InitialAccessTokenLifetime: %s",
+		c.AccessTokenLifetime,
+		c.RefreshTokenLifetime,
+		c.ClientTokenLifetime,
+		c.InitialAccessTokenLifetime,
+	)
+}
+
+type OIDCConfig struct {
+	SigningAlgorithm    string         `toml:"signing_algorithm"`
+	KeyRotationInterval xtime.Duration `toml:"key_rotation_interval"`
+	IDTokenLifetime     xtime.Duration `toml:"id_token_lifetime"`
+}
+
+func (c OIDCConfig) String() string {
+	return fmt.Sprintf("\n SigningAlgorithm: %s\n KeyRotationInterval: %s\n IDTokenLifetime: %s",
+		c.SigningAlgorithm,
+		c.KeyRotationInterval,
+		c.IDTokenLifetime,
+	)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+)
+
+func TestLoginConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LoginConfig
+		wantErr bool
+	}{
+		{name: "six digits", cfg: LoginConfig{CodeLength: 6, CodeAlphabet: xrand.Digits}},
+		{name: "five digits", cfg: LoginConfig{CodeLength: 5, CodeAlphabet: xrand.Digits}, wantErr: true},
+		{name: "single digit", cfg: LoginConfig{CodeLength: 1, CodeAlphabet: xrand.Digits}, wantErr: true},
+		{name: "four alphanumeric characters", cfg: LoginConfig{CodeLength: 4, CodeAlphabet: "0123456789abcdefghijklmnopqrstuvwxyz"}},
+		{name: "three words", cfg: LoginConfig{CodeLength: 3, CodeWords: true}},
+		{name: "two words", cfg: LoginConfig{CodeLength: 2, CodeWords: true}, wantErr: true},
+		{name: "zero length", cfg: LoginConfig{CodeLength: 0, CodeAlphabet: xrand.Digits}, wantErr: true},
+		{name: "single character alphabet", cfg: LoginConfig{CodeLength: 32, CodeAlphabet: "a"}, wantErr: true},
+		{name: "alphabet with dash", cfg: LoginConfig{CodeLength: 8, CodeAlphabet: "0123456789-"}, wantErr: true},
+		{name: "alphabet with both cases", cfg: LoginConfig{CodeLength: 8, CodeAlphabet: "abcdefABCDEF"}, wantErr: true},
+		{name: "alphabet with duplicate character", cfg: LoginConfig{CodeLength: 8, CodeAlphabet: "01234567899"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type AccessToken struct {
+	ID        int        `db:"access_token_id"`
+	Token     string     `db:"access_token_token"`
+	ClientID  string     `db:"access_token_client_id"`
+	UserID    string     `db:"access_token_user_id"`
+	Scope     string     `db:"access_token_scope"`
+	ExpiresAt time.Time  `db:"access_token_expires_at"`
+	CreatedAt time.Time  `db:"access_token_created_at"`
+	RevokedAt *time.Time `db:"access_token_revoked_at"`
+	Family    *string    `db:"access_token_family"`
+}
+
+func (d *Database) InsertAccessToken(ctx context.Context, token AccessToken) error {
+	query := `
+		INSERT INTO access_tokens (access_token_token, access_token_client_id, access_token_user_id, access_token_scope, access_token_expires_at, access_token_family)
+		VALUES (:access_token_token, :access_token_client_id, :access_token_user_id, :access_token_scope, :access_token_expires_at, :access_token_family)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("failed to insert access token: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetAccessToken(ctx context.Context, token string) (*AccessToken, error) {
+	query := `
+		SELECT *
+		FROM access_tokens
+		WHERE access_token_token = $1
+		AND access_token_expires_at > now()
+		AND access_token_revoked_at IS NULL
+	`
+
+	var accessToken AccessToken
+	if err := d.db.GetContext(ctx, &accessToken, query, token); err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	return &accessToken, nil
+}
+
+func (d *Database) RevokeAccessToken(ctx context.Context, clientID string, token string) (int, error) {
+	query := `
+		UPDATE access_tokens
+		SET access_token_revoked_at = now()
+		WHERE access_token_client_id = $1
+		AND access_token_token = $2
+		AND access_token_revoked_at IS NULL
+	`
+
+	res, err := d.db.ExecContext(ctx, query, clientID, token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+// RevokeAccessTokensByFamily revokes the access tokens of a refresh token family.
+func (d *Database) RevokeAccessTokensByFamily(ctx context.Context, clientID string, family string) error {
+	query := `
+		UPDATE access_tokens
+		SET access_token_revoked_at = now()
+		WHERE access_token_client_id = $1
+		AND access_token_family = $2
+		AND access_token_revoked_at IS NULL
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, clientID, family); err != nil {
+		return fmt.Errorf("failed to revoke access tokens by family: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteExpiredAccessTokens(ctx context.Context) error {
+	query := `
+		DELETE FROM access_tokens
+		WHERE access_token_expires_at < now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete expired access tokens: %w", err)
+	}
+
+	return nil
+}
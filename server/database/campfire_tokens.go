@@ -34,7 +34,7 @@ func (d *Database) GetCampfireTokens(ctx context.Context) ([]CampfireToken, erro
 func (d *Database) GetNextCampfireToken(ctx context.Context) (*CampfireToken, error) {
 	query := `SELECT * FROM campfire_tokens WHERE campfire_token_expires_at > $1 ORDER BY campfire_token_expires_at LIMIT 1`
 
-	now := time.Now().Add(time.Minute)
+	now := time.Now().UTC().Add(time.Minute)
 
 	var campfireToken CampfireToken
 	if err := d.db.GetContext(ctx, &campfireToken, query, now); err != nil {
@@ -57,7 +57,7 @@ func (d *Database) DeleteExpiredCampfireTokens(ctx context.Context) (int, error)
 func (d *Database) GetCampfireTokensExpiringSoon(ctx context.Context, within time.Duration) ([]CampfireToken, error) {
 	query := `SELECT * FROM campfire_tokens WHERE campfire_token_expires_at > $1 AND campfire_token_expires_at < $2 ORDER BY campfire_token_expires_at`
 
-	now := time.Now()
+	now := time.Now().UTC()
 	later := now.Add(within)
 
 	var tokens []CampfireToken
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ClientChannel is a club channel logins of a client are allowed to be verified in.
+type ClientChannel struct {
+	ClientID  string    `db:"client_channel_client_id"`
+	ClubID    string    `db:"client_channel_club_id"`
+	ChannelID string    `db:"client_channel_channel_id"`
+	Default   bool      `db:"client_channel_default"`
+	CreatedAt time.Time `db:"client_channel_created_at"`
+}
+
+func (d *Database) InsertClientChannel(ctx context.Context, channel ClientChannel) error {
+	query := `
+		INSERT INTO client_channels (client_channel_client_id, client_channel_club_id, client_channel_channel_id)
+		VALUES (:client_channel_client_id, :client_channel_club_id, :client_channel_channel_id)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, channel); err != nil {
+		return fmt.Errorf("failed to insert client channel: %w", err)
+	}
+
+	if channel.Default {
+		return d.UpdateClientDefaultChannel(ctx, channel.ClientID, channel.ClubID, channel.ChannelID)
+	}
+
+	return nil
+}
+
+// GetClientChannels returns all channels of a client, the default channel first.
+func (d *Database) GetClientChannels(ctx context.Context, clientID string) ([]ClientChannel, error) {
+	query := `
+		SELECT *
+		FROM client_channels
+		WHERE client_channel_client_id = $1
+		ORDER BY client_channel_default DESC, client_channel_created_at
+	`
+
+	var channels []ClientChannel
+	if err := d.db.SelectContext(ctx, &channels, query, clientID); err != nil {
+		return nil, fmt.Errorf("failed to get client channels: %w", err)
+	}
+
+	return channels, nil
+}
+
+// UpdateClientDefaultChannel makes the given channel the only default channel of the client.
+func (d *Database) UpdateClientDefaultChannel(ctx context.Context, clientID string, clubID string, channelID string) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// the old default has to be unset first, only one default channel per client is allowed
+	unsetQuery := `
+		UPDATE client_channels
+		SET client_channel_default = false
+		WHERE client_channel_client_id = $1
+		AND client_channel_default
+	`
+	if _, err = tx.ExecContext(ctx, unsetQuery, clientID); err != nil {
+		return fmt.Errorf("failed to unset client default channel: %w", err)
+	}
+
+	setQuery := `
+		UPDATE client_channels
+		SET client_channel_default = true
+		WHERE client_channel_client_id = $1
+		AND client_channel_club_id = $2
+		AND client_channel_channel_id = $3
+	`
+	if _, err = tx.ExecContext(ctx, setQuery, clientID, clubID, channelID); err != nil {
+		return fmt.Errorf("failed to set client default channel: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (d *Database) DeleteClientChannel(ctx context.Context, clientID string, clubID string, channelID string) error {
+	query := `
+		DELETE FROM client_channels
+		WHERE client_channel_client_id = $1
+		AND client_channel_club_id = $2
+		AND client_channel_channel_id = $3
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, clientID, clubID, channelID); err != nil {
+		return fmt.Errorf("failed to delete client channel: %w", err)
+	}
+
+	return nil
+}
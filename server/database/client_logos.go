@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateClientLogo sets the PNG logo of the client shown on the login pages.
+func (d *Database) UpdateClientLogo(ctx context.Context, clientID string, logo []byte) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	logoQuery := `
+		INSERT INTO client_logos (client_logo_client_id, client_logo_image)
+		VALUES ($1, $2)
+		ON CONFLICT (client_logo_client_id) DO UPDATE
+		SET client_logo_image = EXCLUDED.client_logo_image
+	`
+	if _, err = tx.ExecContext(ctx, logoQuery, clientID, logo); err != nil {
+		return fmt.Errorf("failed to upsert client logo: %w", err)
+	}
+
+	clientQuery := `
+		UPDATE clients
+		SET client_logo_updated_at = now()
+		WHERE client_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, clientQuery, clientID); err != nil {
+		return fmt.Errorf("failed to update client logo updated at: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (d *Database) GetClientLogo(ctx context.Context, clientID string) ([]byte, error) {
+	query := `
+		SELECT client_logo_image
+		FROM client_logos
+		WHERE client_logo_client_id = $1
+	`
+
+	var logo []byte
+	if err := d.db.GetContext(ctx, &logo, query, clientID); err != nil {
+		return nil, fmt.Errorf("failed to get client logo: %w", err)
+	}
+
+	return logo, nil
+}
+
+func (d *Database) DeleteClientLogo(ctx context.Context, clientID string) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	logoQuery := `
+		DELETE FROM client_logos
+		WHERE client_logo_client_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, logoQuery, clientID); err != nil {
+		return fmt.Errorf("failed to delete client logo: %w", err)
+	}
+
+	clientQuery := `
+		UPDATE clients
+		SET client_logo_updated_at = NULL
+		WHERE client_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, clientQuery, clientID); err != nil {
+		return fmt.Errorf("failed to update client logo updated at: %w", err)
+	}
+
+	return tx.Commit()
+}
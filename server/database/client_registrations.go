@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInitialAccessTokenUsed is returned when the initial access token was already used or has expired.
+var ErrInitialAccessTokenUsed = errors.New("initial access token already used")
+
+// InsertClientRegistration inserts a registered client and uses up its initial access token.
+func (d *Database) InsertClientRegistration(ctx context.Context, client Client, channel *ClientChannel, initialAccessTokenID int) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	tokenQuery := `
+		DELETE FROM initial_access_tokens
+		WHERE initial_access_token_id = $1
+		AND initial_access_token_expires_at > now()
+	`
+	res, err := tx.ExecContext(ctx, tokenQuery, initialAccessTokenID)
+	if err != nil {
+		return fmt.Errorf("failed to delete initial access token: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get deleted initial access token rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrInitialAccessTokenUsed
+	}
+
+	clientQuery := `
+		INSERT INTO clients (client_name, client_id, client_secret, client_redirect_uris, client_public, client_scopes, client_registration_access_token)
+		VALUES (:client_name, :client_id, :client_secret, :client_redirect_uris, :client_public, :client_scopes, :client_registration_access_token)
+	`
+	if _, err = tx.NamedExecContext(ctx, clientQuery, client); err != nil {
+		return fmt.Errorf("failed to insert client: %w", err)
+	}
+
+	if err = insertDefaultClientChannel(ctx, tx, client.ID, channel); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateClientRegistration replaces the registered metadata and the channels of a client.
+func (d *Database) UpdateClientRegistration(ctx context.Context, client Client, channel *ClientChannel) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	clientQuery := `
+		UPDATE clients
+		SET client_name = :client_name,
+			client_redirect_uris = :client_redirect_uris,
+			client_scopes = :client_scopes
+		WHERE client_id = :client_id
+	`
+	if _, err = tx.NamedExecContext(ctx, clientQuery, client); err != nil {
+		return fmt.Errorf("failed to update client: %w", err)
+	}
+
+	channelsQuery := `
+		DELETE FROM client_channels
+		WHERE client_channel_client_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, channelsQuery, client.ID); err != nil {
+		return fmt.Errorf("failed to delete client channels: %w", err)
+	}
+
+	if err = insertDefaultClientChannel(ctx, tx, client.ID, channel); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertDefaultClientChannel(ctx context.Context, tx *sqlx.Tx, clientID string, channel *ClientChannel) error {
+	if channel == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO client_channels (client_channel_client_id, client_channel_club_id, client_channel_channel_id, client_channel_default)
+		VALUES ($1, $2, $3, true)
+	`
+	if _, err := tx.ExecContext(ctx, query, clientID, channel.ClubID, channel.ChannelID); err != nil {
+		return fmt.Errorf("failed to insert client channel: %w", err)
+	}
+
+	return nil
+}
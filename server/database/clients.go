@@ -5,6 +5,14 @@ import (
 	"time"
 
 	"github.com/topi314/campfire-auth/internal/xpgtype"
+	"github.com/topi314/campfire-auth/internal/xtime"
+)
+
+const (
+	// VerificationModeChannelCode lets the user post the login code in the channel.
+	VerificationModeChannelCode = "channel_code"
+	// VerificationModeMention mentions the user with the login code in the channel.
+	VerificationModeMention = "mention"
 )
 
 type Client struct {
@@ -12,16 +20,59 @@ type Client struct {
 	Name         string                 `db:"client_name"`
 	Secret       string                 `db:"client_secret"`
 	RedirectURIs xpgtype.JSON[[]string] `db:"client_redirect_uris"`
+	Public       bool                   `db:"client_public"`
+	Scopes       xpgtype.JSON[[]string] `db:"client_scopes"`
 	CreatedAt    time.Time              `db:"client_created_at"`
+
+	LoginCodeLifetime     *xtime.Duration `db:"client_login_code_lifetime"`
+	LoginVerifiedLifetime *xtime.Duration `db:"client_login_verified_lifetime"`
+	LoginExchangeLifetime *xtime.Duration `db:"client_login_exchange_lifetime"`
+
+	RequireClubMember bool    `db:"client_require_club_member"`
+	MinClubRole       *string `db:"client_min_club_role"`
+
+	VerificationMode string `db:"client_verification_mode"`
+
+	DisplayName   *string    `db:"client_display_name"`
+	HomepageURL   *string    `db:"client_homepage_url"`
+	PrivacyURL    *string    `db:"client_privacy_url"`
+	AccentColor   *string    `db:"client_accent_color"`
+	LogoUpdatedAt *time.Time `db:"client_logo_updated_at"`
+
+	RegistrationAccessToken *string `db:"client_registration_access_token"`
 }
 
-func (d *Database) InsertClient(ctx context.Context, name string, clientID string, clientSecret string, redirectURIs []string) error {
+func (d *Database) InsertClient(ctx context.Context, client Client) error {
 	query := `
-		INSERT INTO clients (name, client_id, client_secret, client_redirect_uris)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO clients (client_name, client_id, client_secret, client_redirect_uris, client_public, client_scopes, client_registration_access_token)
+		VALUES (:client_name, :client_id, :client_secret, :client_redirect_uris, :client_public, :client_scopes, :client_registration_access_token)
 	`
 
-	_, err := d.db.ExecContext(ctx, query, name, clientID, clientSecret, xpgtype.JSON[[]string]{V: redirectURIs})
+	_, err := d.db.NamedExecContext(ctx, query, client)
+
+	return err
+}
+
+func (d *Database) UpdateClient(ctx context.Context, client Client) error {
+	query := `
+		UPDATE clients
+		SET client_name = :client_name,
+			client_redirect_uris = :client_redirect_uris,
+			client_scopes = :client_scopes,
+			client_login_code_lifetime = :client_login_code_lifetime,
+			client_login_verified_lifetime = :client_login_verified_lifetime,
+			client_login_exchange_lifetime = :client_login_exchange_lifetime,
+			client_require_club_member = :client_require_club_member,
+			client_min_club_role = :client_min_club_role,
+			client_verification_mode = :client_verification_mode,
+			client_display_name = :client_display_name,
+			client_homepage_url = :client_homepage_url,
+			client_privacy_url = :client_privacy_url,
+			client_accent_color = :client_accent_color
+		WHERE client_id = :client_id
+	`
+
+	_, err := d.db.NamedExecContext(ctx, query, client)
 
 	return err
 }
@@ -60,7 +111,7 @@ func (d *Database) GetClientByIDSecret(ctx context.Context, clientID, clientSecr
 	query := `
 		SELECT *
 		FROM clients
-		WHERE client_id = $1 AND client_secret = $2
+		WHERE client_id = $1 AND client_secret = $2 AND NOT client_public
 	`
 
 	var client Client
@@ -70,3 +121,29 @@ func (d *Database) GetClientByIDSecret(ctx context.Context, clientID, clientSecr
 
 	return &client, nil
 }
+
+func (d *Database) GetClientByIDRegistrationAccessToken(ctx context.Context, clientID, registrationAccessToken string) (*Client, error) {
+	query := `
+		SELECT *
+		FROM clients
+		WHERE client_id = $1 AND client_registration_access_token = $2
+	`
+
+	var client Client
+	if err := d.db.GetContext(ctx, &client, query, clientID, registrationAccessToken); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (d *Database) DeleteClient(ctx context.Context, clientID string) error {
+	query := `
+		DELETE FROM clients
+		WHERE client_id = $1
+	`
+
+	_, err := d.db.ExecContext(ctx, query, clientID)
+
+	return err
+}
@@ -25,8 +25,9 @@ func (c Config) String() string {
 	)
 }
 
+// DataSourceName returns the connection string of the database.
 func (c Config) DataSourceName() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
 		c.Host,
 		c.Port,
 		c.Username,
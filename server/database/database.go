@@ -3,9 +3,11 @@ package database
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/topi314/gomigrate"
@@ -45,3 +47,11 @@ func (d *Database) Close() error {
 	}
 	return nil
 }
+
+// uniqueViolationCode is the postgres error code of unique constraint violations.
+const uniqueViolationCode = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
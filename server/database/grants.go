@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Grant is the authorization a user gave a client.
+type Grant struct {
+	ClientID  string    `db:"grant_client_id"`
+	UserID    string    `db:"grant_user_id"`
+	Scope     string    `db:"grant_scope"`
+	CreatedAt time.Time `db:"grant_created_at"`
+	UpdatedAt time.Time `db:"grant_updated_at"`
+}
+
+// UserGrant is a grant together with the name of its client.
+type UserGrant struct {
+	Grant
+	ClientName string `db:"client_name"`
+}
+
+func (d *Database) UpsertGrant(ctx context.Context, clientID string, userID string, scope string) error {
+	query := `
+		INSERT INTO grants (grant_client_id, grant_user_id, grant_scope)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (grant_client_id, grant_user_id) DO UPDATE
+		SET grant_scope = EXCLUDED.grant_scope,
+			grant_updated_at = now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, clientID, userID, scope); err != nil {
+		return fmt.Errorf("failed to upsert grant: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetGrant(ctx context.Context, clientID string, userID string) (*Grant, error) {
+	query := `
+		SELECT *
+		FROM grants
+		WHERE grant_client_id = $1
+		AND grant_user_id = $2
+	`
+
+	var grant Grant
+	if err := d.db.GetContext(ctx, &grant, query, clientID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get grant: %w", err)
+	}
+
+	return &grant, nil
+}
+
+func (d *Database) GetUserGrants(ctx context.Context, userID string) ([]UserGrant, error) {
+	query := `
+		SELECT grants.*, clients.client_name
+		FROM grants
+		JOIN clients ON clients.client_id = grants.grant_client_id
+		WHERE grant_user_id = $1
+		ORDER BY grant_updated_at DESC
+	`
+
+	var grants []UserGrant
+	if err := d.db.SelectContext(ctx, &grants, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user grants: %w", err)
+	}
+
+	return grants, nil
+}
+
+// RevokeGrant deletes the grant and revokes all tokens and verified logins the client got for the user.
+func (d *Database) RevokeGrant(ctx context.Context, clientID string, userID string) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	grantQuery := `
+		DELETE FROM grants
+		WHERE grant_client_id = $1
+		AND grant_user_id = $2
+	`
+	if _, err = tx.ExecContext(ctx, grantQuery, clientID, userID); err != nil {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+
+	accessTokensQuery := `
+		UPDATE access_tokens
+		SET access_token_revoked_at = now()
+		WHERE access_token_client_id = $1
+		AND access_token_user_id = $2
+		AND access_token_revoked_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, accessTokensQuery, clientID, userID); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	refreshTokensQuery := `
+		UPDATE refresh_tokens
+		SET refresh_token_revoked_at = now()
+		WHERE refresh_token_client_id = $1
+		AND refresh_token_user_id = $2
+		AND refresh_token_revoked_at IS NULL
+	`
+	if _, err = tx.ExecContext(ctx, refreshTokensQuery, clientID, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	// verified logins which were not exchanged yet would otherwise still issue new tokens
+	loginsQuery := `
+		DELETE FROM logins
+		WHERE login_client_id = $1
+		AND login_user->>'id' = $2
+	`
+	if _, err = tx.ExecContext(ctx, loginsQuery, clientID, userID); err != nil {
+		return fmt.Errorf("failed to delete logins: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// InitialAccessToken authorizes the dynamic registration of clients as described in RFC 7591 section 3.
+type InitialAccessToken struct {
+	ID        int       `db:"initial_access_token_id"`
+	Token     string    `db:"initial_access_token_token"`
+	Name      string    `db:"initial_access_token_name"`
+	ExpiresAt time.Time `db:"initial_access_token_expires_at"`
+	CreatedAt time.Time `db:"initial_access_token_created_at"`
+}
+
+func (d *Database) InsertInitialAccessToken(ctx context.Context, token InitialAccessToken) error {
+	query := `
+		INSERT INTO initial_access_tokens (initial_access_token_token, initial_access_token_name, initial_access_token_expires_at)
+		VALUES (:initial_access_token_token, :initial_access_token_name, :initial_access_token_expires_at)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("failed to insert initial access token: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetInitialAccessTokens(ctx context.Context) ([]InitialAccessToken, error) {
+	query := `
+		SELECT *
+		FROM initial_access_tokens
+		ORDER BY initial_access_token_created_at DESC
+	`
+
+	var tokens []InitialAccessToken
+	if err := d.db.SelectContext(ctx, &tokens, query); err != nil {
+		return nil, fmt.Errorf("failed to get initial access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (d *Database) GetInitialAccessToken(ctx context.Context, token string) (*InitialAccessToken, error) {
+	query := `
+		SELECT *
+		FROM initial_access_tokens
+		WHERE initial_access_token_token = $1
+		AND initial_access_token_expires_at > now()
+	`
+
+	var initialAccessToken InitialAccessToken
+	if err := d.db.GetContext(ctx, &initialAccessToken, query, token); err != nil {
+		return nil, fmt.Errorf("failed to get initial access token: %w", err)
+	}
+
+	return &initialAccessToken, nil
+}
+
+func (d *Database) DeleteInitialAccessToken(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM initial_access_tokens
+		WHERE initial_access_token_id = $1
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete initial access token: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteExpiredInitialAccessTokens(ctx context.Context) error {
+	query := `
+		DELETE FROM initial_access_tokens
+		WHERE initial_access_token_expires_at < now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete expired initial access tokens: %w", err)
+	}
+
+	return nil
+}
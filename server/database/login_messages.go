@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// loginMessageRetentionSkew keeps used messages a bit longer than the longest code lifetime.
+const loginMessageRetentionSkew = time.Minute
+
+// insertLoginMessage marks a message as used.
+func insertLoginMessage(ctx context.Context, tx *sqlx.Tx, messageID string, senderID string) error {
+	query := `
+		INSERT INTO login_messages (login_message_id, login_message_sender_id)
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, messageID, senderID); err != nil {
+		if isUniqueViolation(err) {
+			return ErrLoginMessageUsed
+		}
+		return fmt.Errorf("failed to insert login message: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsedLoginMessageIDs returns which of the given messages already verified a login.
+func (d *Database) GetUsedLoginMessageIDs(ctx context.Context, messageIDs []string) ([]string, error) {
+	query := `
+		SELECT login_message_id
+		FROM login_messages
+		WHERE login_message_id = ANY($1)
+	`
+
+	var usedIDs []string
+	if err := d.db.SelectContext(ctx, &usedIDs, query, messageIDs); err != nil {
+		return nil, fmt.Errorf("failed to get used login message ids: %w", err)
+	}
+
+	return usedIDs, nil
+}
+
+// DeleteExpiredLoginMessages deletes used messages older than the longest code lifetime.
+func (d *Database) DeleteExpiredLoginMessages(ctx context.Context, codeLifetime time.Duration) error {
+	query := `
+		DELETE FROM login_messages
+		WHERE login_message_used_at < now() - make_interval(secs => (
+			SELECT GREATEST($1, MAX(client_login_code_lifetime))
+			FROM clients
+		) + $2)
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, codeLifetime.Seconds(), loginMessageRetentionSkew.Seconds()); err != nil {
+		return fmt.Errorf("failed to delete expired login messages: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const loginUpdatesChannel = "login_updates"
+
+// ListenLoginUpdates calls fn with the ID of every verified login until the context is canceled.
+func (d *Database) ListenLoginUpdates(ctx context.Context, fn func(loginID int)) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("listening requires a pgx connection")
+		}
+		pgxConn := stdlibConn.Conn()
+
+		if _, err = pgxConn.Exec(ctx, "LISTEN "+loginUpdatesChannel); err != nil {
+			return fmt.Errorf("failed to listen for login updates: %w", err)
+		}
+
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to wait for login update: %w", err)
+			}
+
+			loginID, err := strconv.Atoi(notification.Payload)
+			if err != nil {
+				continue
+			}
+			fn(loginID)
+		}
+	})
+}
@@ -3,23 +3,72 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
 
+var (
+	// ErrLoginCodeUsed is returned when a generated code is already used by another login.
+	ErrLoginCodeUsed = errors.New("login code already used")
+	// ErrLoginMessageUsed is returned when the message of a login already verified another login.
+	ErrLoginMessageUsed = errors.New("login message already used")
+	// ErrLoginMentioned is returned when the user of a mention login was already mentioned.
+	ErrLoginMentioned = errors.New("login already mentioned")
+)
+
 type Login struct {
-	ID           int              `db:"login_id"`
-	ClientID     string           `db:"login_client_id"`
-	Code         string           `db:"login_code"`
-	CheckCode    string           `db:"login_check_code"`
-	ExchangeCode string           `db:"login_exchange_code"`
-	RedirectURI  string           `db:"login_redirect_uri"`
-	ClubID       string           `db:"login_club_id"`
-	ChannelID    string           `db:"login_channel_id"`
-	State        string           `db:"login_state"`
-	User         *json.RawMessage `db:"login_user"`
-	CreatedAt    time.Time        `db:"login_created_at"`
-	UpdatedAt    time.Time        `db:"login_updated_at"`
+	ID                  int              `db:"login_id"`
+	ClientID            string           `db:"login_client_id"`
+	Code                string           `db:"login_code"`
+	CheckCode           string           `db:"login_check_code"`
+	ExchangeCode        string           `db:"login_exchange_code"`
+	RedirectURI         string           `db:"login_redirect_uri"`
+	ClubID              string           `db:"login_club_id"`
+	ChannelID           string           `db:"login_channel_id"`
+	State               string           `db:"login_state"`
+	Scope               string           `db:"login_scope"`
+	Nonce               string           `db:"login_nonce"`
+	CodeChallenge       string           `db:"login_code_challenge"`
+	CodeChallengeMethod string           `db:"login_code_challenge_method"`
+	Device              bool             `db:"login_device"`
+	DevicePolledAt      *time.Time       `db:"login_device_polled_at"`
+	API                 bool             `db:"login_api"`
+	GrantedScope        *string          `db:"login_granted_scope"`
+	SAMLRequestID       *string          `db:"login_saml_request_id"`
+	UILocales           string           `db:"login_ui_locales"`
+	Mention             bool             `db:"login_mention"`
+	MentionUserID       *string          `db:"login_mention_user_id"`
+	MentionAttempts     int              `db:"login_mention_attempts"`
+	User                *json.RawMessage `db:"login_user"`
+	ClubMember          *json.RawMessage `db:"login_club_member"`
+	MessageID           *string          `db:"login_message_id"`
+	MessageSenderID     *string          `db:"login_message_sender_id"`
+	VerifiedAt          *time.Time       `db:"login_verified_at"`
+	ExpiresAt           time.Time        `db:"login_expires_at"`
+	CreatedAt           time.Time        `db:"login_created_at"`
+	UpdatedAt           time.Time        `db:"login_updated_at"`
+}
+
+// LoginLifetimes are the lifetimes of the states of a login.
+type LoginLifetimes struct {
+	Code     time.Duration
+	Verified time.Duration
+	Exchange time.Duration
+}
+
+// ForClient applies the lifetime overrides of the client.
+func (l LoginLifetimes) ForClient(client Client) LoginLifetimes {
+	if client.LoginCodeLifetime != nil {
+		l.Code = time.Duration(*client.LoginCodeLifetime)
+	}
+	if client.LoginVerifiedLifetime != nil {
+		l.Verified = time.Duration(*client.LoginVerifiedLifetime)
+	}
+	if client.LoginExchangeLifetime != nil {
+		l.Exchange = time.Duration(*client.LoginExchangeLifetime)
+	}
+	return l
 }
 
 type LoginWithClient struct {
@@ -27,17 +76,37 @@ type LoginWithClient struct {
 	Client
 }
 
-func (d *Database) InsertLogin(ctx context.Context, login Login) error {
+// InsertLogin inserts a new pending login and returns its ID.
+func (d *Database) InsertLogin(ctx context.Context, login Login, codeLifetime time.Duration) (int, error) {
 	query := `
-		INSERT INTO logins (login_client_id, login_code, login_check_code, login_exchange_code, login_redirect_uri, login_club_id, login_channel_id, login_state)
-		VALUES (:login_client_id, :login_code, :login_check_code, :login_exchange_code, :login_redirect_uri, :login_club_id, :login_channel_id, :login_state)
+		INSERT INTO logins (login_client_id, login_code, login_check_code, login_exchange_code, login_redirect_uri, login_club_id, login_channel_id, login_state, login_scope, login_nonce, login_code_challenge, login_code_challenge_method, login_device, login_api, login_saml_request_id, login_ui_locales, login_mention, login_expires_at)
+		VALUES (:login_client_id, :login_code, :login_check_code, :login_exchange_code, :login_redirect_uri, :login_club_id, :login_channel_id, :login_state, :login_scope, :login_nonce, :login_code_challenge, :login_code_challenge_method, :login_device, :login_api, :login_saml_request_id, :login_ui_locales, :login_mention, now() + make_interval(secs => :code_lifetime))
+		RETURNING login_id
 	`
 
-	if _, err := d.db.NamedExecContext(ctx, query, login); err != nil {
-		return fmt.Errorf("failed to insert login: %w", err)
+	arg := struct {
+		Login
+		CodeLifetime float64 `db:"code_lifetime"`
+	}{
+		Login:        login,
+		CodeLifetime: codeLifetime.Seconds(),
 	}
 
-	return nil
+	stmt, err := d.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare insert login: %w", err)
+	}
+	defer stmt.Close()
+
+	var id int
+	if err = stmt.GetContext(ctx, &id, arg); err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrLoginCodeUsed
+		}
+		return 0, fmt.Errorf("failed to insert login: %w", err)
+	}
+
+	return id, nil
 }
 
 func (d *Database) GetLoginByCheckCode(ctx context.Context, checkCode string) (*Login, error) {
@@ -45,6 +114,7 @@ func (d *Database) GetLoginByCheckCode(ctx context.Context, checkCode string) (*
 		SELECT *
 		FROM logins
 		WHERE logins.login_check_code = $1
+		AND logins.login_expires_at > now()
 	`
 
 	var login Login
@@ -60,6 +130,7 @@ func (d *Database) GetLoginByCode(ctx context.Context, code string) (*Login, err
 		SELECT *
 		FROM logins
 		WHERE logins.login_code = $1
+		AND logins.login_expires_at > now()
 	`
 
 	var login Login
@@ -70,24 +141,162 @@ func (d *Database) GetLoginByCode(ctx context.Context, code string) (*Login, err
 	return &login, nil
 }
 
-func (d *Database) UpdateLoginUsers(ctx context.Context, logins map[int]json.RawMessage) error {
+// LoginUser is the user who posted the code of a login.
+type LoginUser struct {
+	User       json.RawMessage
+	ClubMember *json.RawMessage
+	MessageID  string
+	SenderID   string
+}
+
+// UpdateLoginUsers verifies the logins.
+func (d *Database) UpdateLoginUsers(ctx context.Context, logins map[int]LoginUser, lifetimes LoginLifetimes) error {
 	for id, user := range logins {
-		if err := d.UpdateLoginUser(ctx, id, user); err != nil {
+		if err := d.UpdateLoginUser(ctx, id, user, lifetimes); err != nil {
+			if errors.Is(err, ErrLoginMessageUsed) {
+				continue
+			}
 			return fmt.Errorf("failed to update login user for id %d: %w", id, err)
 		}
 	}
 	return nil
 }
 
-func (d *Database) UpdateLoginUser(ctx context.Context, id int, user json.RawMessage) error {
+// UpdateLoginUser sets the verified user of a login.
+func (d *Database) UpdateLoginUser(ctx context.Context, id int, user LoginUser, lifetimes LoginLifetimes) error {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if user.MessageID != "" {
+		if err = insertLoginMessage(ctx, tx, user.MessageID, user.SenderID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		WITH updated AS (
+			UPDATE logins
+			SET login_user = $2,
+				login_club_member = $5,
+				login_message_id = NULLIF($6, ''),
+				login_message_sender_id = NULLIF($7, ''),
+				login_verified_at = now(),
+				login_granted_scope = CASE WHEN login_device OR login_api THEN login_scope END,
+				login_expires_at = now() + make_interval(secs => CASE
+					WHEN login_device OR login_api THEN COALESCE(clients.client_login_exchange_lifetime, $4)
+					ELSE COALESCE(clients.client_login_verified_lifetime, $3)
+				END)
+			FROM clients
+			WHERE login_id = $1
+			AND clients.client_id = logins.login_client_id
+			RETURNING login_id
+		)
+		SELECT pg_notify('` + loginUpdatesChannel + `', login_id::text)
+		FROM updated
+	`
+
+	if _, err = tx.ExecContext(ctx, query, id, user.User, lifetimes.Verified.Seconds(), lifetimes.Exchange.Seconds(), user.ClubMember, user.MessageID, user.SenderID); err != nil {
+		return fmt.Errorf("failed to update login user: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateLoginMention claims a mention login for the user who is mentioned to verify it.
+func (d *Database) UpdateLoginMention(ctx context.Context, id int, userID string) error {
 	query := `
 		UPDATE logins
-		SET login_user = $2
+		SET login_mention_user_id = $2
 		WHERE login_id = $1
+		AND login_mention
+		AND login_mention_user_id IS NULL
+		AND login_user IS NULL
 	`
 
-	if _, err := d.db.ExecContext(ctx, query, id, user); err != nil {
-		return fmt.Errorf("failed to update login user: %w", err)
+	res, err := d.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update login mention: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get updated login mention rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrLoginMentioned
+	}
+
+	return nil
+}
+
+// UpdateLoginMentionMessage stores the message which mentioned the user of a mention login.
+func (d *Database) UpdateLoginMentionMessage(ctx context.Context, id int, messageID string) error {
+	query := `
+		UPDATE logins
+		SET login_message_id = $2
+		WHERE login_id = $1
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id, messageID); err != nil {
+		return fmt.Errorf("failed to update login mention message: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseLoginMention releases the claim of a mention login when the user could not be mentioned.
+func (d *Database) ReleaseLoginMention(ctx context.Context, id int, userID string) error {
+	query := `
+		UPDATE logins
+		SET login_mention_user_id = NULL
+		WHERE login_id = $1
+		AND login_mention_user_id = $2
+		AND login_message_id IS NULL
+		AND login_user IS NULL
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id, userID); err != nil {
+		return fmt.Errorf("failed to release login mention: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementLoginMentionAttempts counts a wrong code entered for a mention login.
+func (d *Database) IncrementLoginMentionAttempts(ctx context.Context, id int) (int, error) {
+	query := `
+		UPDATE logins
+		SET login_mention_attempts = login_mention_attempts + 1
+		WHERE login_id = $1
+		RETURNING login_mention_attempts
+	`
+
+	var attempts int
+	if err := d.db.GetContext(ctx, &attempts, query, id); err != nil {
+		return 0, fmt.Errorf("failed to increment login mention attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// UpdateLoginGrantedScope sets the scope the user granted.
+func (d *Database) UpdateLoginGrantedScope(ctx context.Context, id int, scope string, lifetimes LoginLifetimes) error {
+	query := `
+		UPDATE logins
+		SET login_granted_scope = $2,
+			login_expires_at = now() + make_interval(secs => COALESCE(clients.client_login_exchange_lifetime, $3))
+		FROM clients
+		WHERE login_id = $1
+		AND login_user IS NOT NULL
+		AND clients.client_id = logins.login_client_id
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id, scope, lifetimes.Exchange.Seconds()); err != nil {
+		return fmt.Errorf("failed to update login granted scope: %w", err)
 	}
 
 	return nil
@@ -100,7 +309,10 @@ func (d *Database) DeleteLoginByClientIDSecretExchangeCode(ctx context.Context,
 		WHERE logins.login_client_id = clients.client_id
 		AND clients.client_id = $1
 		AND clients.client_secret = $2
+		AND NOT clients.client_public
 		AND logins.login_exchange_code = $3
+		AND logins.login_granted_scope IS NOT NULL
+		AND logins.login_expires_at > now()
 		RETURNING logins.*
 	`
 
@@ -112,12 +324,132 @@ func (d *Database) DeleteLoginByClientIDSecretExchangeCode(ctx context.Context,
 	return &login, nil
 }
 
+// DeleteSAMLLogin deletes a verified SAML login once its assertion was issued.
+func (d *Database) DeleteSAMLLogin(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM logins
+		WHERE login_id = $1
+		AND login_saml_request_id IS NOT NULL
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete saml login: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetDeviceLoginByClientIDExchangeCode(ctx context.Context, clientID, exchangeCode string) (*Login, error) {
+	query := `
+		SELECT *
+		FROM logins
+		WHERE login_client_id = $1
+		AND login_exchange_code = $2
+		AND login_device
+	`
+
+	var login Login
+	if err := d.db.GetContext(ctx, &login, query, clientID, exchangeCode); err != nil {
+		return nil, fmt.Errorf("failed to get device login by client ID and exchange code: %w", err)
+	}
+
+	return &login, nil
+}
+
+func (d *Database) UpdateLoginDevicePolledAt(ctx context.Context, id int) error {
+	query := `
+		UPDATE logins
+		SET login_device_polled_at = now()
+		WHERE login_id = $1
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update login device polled at: %w", err)
+	}
+
+	return nil
+}
+
+// GetLoginByClientIDExchangeCode returns the login which can be exchanged with the exchange code.
+func (d *Database) GetLoginByClientIDExchangeCode(ctx context.Context, clientID, exchangeCode string) (*Login, error) {
+	query := `
+		SELECT *
+		FROM logins
+		WHERE login_client_id = $1
+		AND login_exchange_code = $2
+		AND login_user IS NOT NULL
+		AND login_granted_scope IS NOT NULL
+		AND login_expires_at > now()
+	`
+
+	var login Login
+	if err := d.db.GetContext(ctx, &login, query, clientID, exchangeCode); err != nil {
+		return nil, fmt.Errorf("failed to get login by client ID and exchange code: %w", err)
+	}
+
+	return &login, nil
+}
+
+func (d *Database) DeleteLoginByClientIDExchangeCode(ctx context.Context, clientID, exchangeCode string) (*Login, error) {
+	query := `
+		DELETE FROM logins
+		WHERE login_client_id = $1
+		AND login_exchange_code = $2
+		AND login_user IS NOT NULL
+		AND login_granted_scope IS NOT NULL
+		AND login_expires_at > now()
+		RETURNING *
+	`
+
+	var login Login
+	if err := d.db.GetContext(ctx, &login, query, clientID, exchangeCode); err != nil {
+		return nil, fmt.Errorf("failed to delete login by client ID and exchange code: %w", err)
+	}
+
+	return &login, nil
+}
+
+// GetLoginByClientIDID returns the login of the client including expired ones.
+func (d *Database) GetLoginByClientIDID(ctx context.Context, clientID string, id int) (*Login, error) {
+	query := `
+		SELECT *
+		FROM logins
+		WHERE login_client_id = $1
+		AND login_id = $2
+	`
+
+	var login Login
+	if err := d.db.GetContext(ctx, &login, query, clientID, id); err != nil {
+		return nil, fmt.Errorf("failed to get login by client ID and ID: %w", err)
+	}
+
+	return &login, nil
+}
+
+func (d *Database) DeleteLoginByClientIDID(ctx context.Context, clientID string, id int) (int, error) {
+	query := `
+		DELETE FROM logins
+		WHERE login_client_id = $1
+		AND login_id = $2
+	`
+
+	res, err := d.db.ExecContext(ctx, query, clientID, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete login by client ID and ID: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
 // GetNextLogins retrieves all logins which have the same channel id and haven't been checked in a whlile.
 func (d *Database) GetNextLogins(ctx context.Context) ([]Login, error) {
 	query := `
 		SELECT *
 		FROM logins
 		WHERE login_user IS NULL
+		AND NOT login_mention
+		AND login_expires_at > now()
 		ORDER BY login_updated_at ASC
 	`
 
@@ -143,13 +475,19 @@ func (d *Database) UpdateLoginsLastUpdatedAt(ctx context.Context, ids []int) err
 	return nil
 }
 
-func (d *Database) DeleteExpiredLogins(ctx context.Context) error {
+// DeleteExpiredLogins deletes expired logins. Expired device logins are kept for another code lifetime.
+func (d *Database) DeleteExpiredLogins(ctx context.Context, codeLifetime time.Duration) error {
 	query := `
 		DELETE FROM logins
-		WHERE login_created_at < now() - INTERVAL '240 seconds'
+		USING clients
+		WHERE login_client_id = client_id
+		  AND login_expires_at < now() - CASE
+			WHEN login_device THEN make_interval(secs => COALESCE(client_login_code_lifetime, $1))
+			ELSE interval '0'
+		  END
 	`
 
-	if _, err := d.db.ExecContext(ctx, query); err != nil {
+	if _, err := d.db.ExecContext(ctx, query, codeLifetime.Seconds()); err != nil {
 		return fmt.Errorf("failed to delete expired logins: %w", err)
 	}
 
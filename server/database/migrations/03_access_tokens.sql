@@ -0,0 +1,9 @@
+CREATE TABLE access_tokens
+(
+    access_token_id         BIGSERIAL PRIMARY KEY,
+    access_token_token      VARCHAR   NOT NULL UNIQUE,
+    access_token_client_id  VARCHAR   NOT NULL REFERENCES clients (client_id) ON DELETE CASCADE,
+    access_token_user_id    VARCHAR   NOT NULL,
+    access_token_expires_at TIMESTAMP NOT NULL,
+    access_token_created_at TIMESTAMP NOT NULL DEFAULT now()
+);
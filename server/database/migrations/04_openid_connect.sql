@@ -0,0 +1,12 @@
+CREATE TABLE signing_keys
+(
+    signing_key_id          VARCHAR PRIMARY KEY,
+    signing_key_algorithm   VARCHAR   NOT NULL,
+    signing_key_private_key BYTEA     NOT NULL,
+    signing_key_created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    signing_key_expires_at  TIMESTAMP NOT NULL
+);
+
+ALTER TABLE logins
+    ADD COLUMN login_scope VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN login_nonce VARCHAR NOT NULL DEFAULT '';
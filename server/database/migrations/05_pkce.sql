@@ -0,0 +1,6 @@
+ALTER TABLE clients
+    ADD COLUMN client_public BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE logins
+    ADD COLUMN login_code_challenge        VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN login_code_challenge_method VARCHAR NOT NULL DEFAULT '';
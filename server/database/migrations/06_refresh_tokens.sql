@@ -0,0 +1,13 @@
+ALTER TABLE access_tokens
+    ADD COLUMN access_token_scope VARCHAR NOT NULL DEFAULT '';
+
+CREATE TABLE refresh_tokens
+(
+    refresh_token_id         BIGSERIAL PRIMARY KEY,
+    refresh_token_token      VARCHAR   NOT NULL UNIQUE,
+    refresh_token_client_id  VARCHAR   NOT NULL REFERENCES clients (client_id) ON DELETE CASCADE,
+    refresh_token_user_id    VARCHAR   NOT NULL,
+    refresh_token_scope      VARCHAR   NOT NULL DEFAULT '',
+    refresh_token_expires_at TIMESTAMP NOT NULL,
+    refresh_token_created_at TIMESTAMP NOT NULL DEFAULT now()
+);
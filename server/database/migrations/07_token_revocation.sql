@@ -0,0 +1,8 @@
+ALTER TABLE access_tokens
+    ADD COLUMN access_token_revoked_at TIMESTAMP,
+    ADD COLUMN access_token_family     VARCHAR;
+
+-- refresh tokens are rotated on use, the family links all tokens issued from the same authorization
+ALTER TABLE refresh_tokens
+    ADD COLUMN refresh_token_revoked_at TIMESTAMP,
+    ADD COLUMN refresh_token_family     VARCHAR NOT NULL DEFAULT '';
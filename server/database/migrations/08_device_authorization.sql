@@ -0,0 +1,3 @@
+ALTER TABLE logins
+    ADD COLUMN login_device           BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN login_device_polled_at TIMESTAMP;
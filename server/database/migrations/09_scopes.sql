@@ -0,0 +1,5 @@
+ALTER TABLE clients
+    ADD COLUMN client_scopes JSONB NOT NULL DEFAULT '["openid", "profile", "badges", "game_profiles"]';
+
+ALTER TABLE logins
+    ADD COLUMN login_granted_scope VARCHAR;
@@ -0,0 +1,7 @@
+ALTER TABLE clients
+    ALTER COLUMN client_scopes SET DEFAULT '["openid", "profile", "badges", "game_profiles", "users:read"]';
+
+-- existing clients already have access to the user API using basic auth
+UPDATE clients
+SET client_scopes = client_scopes || '["users:read"]'
+WHERE NOT client_public;
@@ -0,0 +1,12 @@
+CREATE TABLE initial_access_tokens
+(
+    initial_access_token_id         BIGSERIAL PRIMARY KEY,
+    initial_access_token_token      VARCHAR   NOT NULL UNIQUE,
+    initial_access_token_name       VARCHAR   NOT NULL,
+    initial_access_token_expires_at TIMESTAMP NOT NULL,
+    initial_access_token_created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- clients created by the admin page have no registration access token and can't be managed with the registration API
+ALTER TABLE clients
+    ADD COLUMN client_registration_access_token VARCHAR UNIQUE;
@@ -0,0 +1,21 @@
+CREATE TABLE saml_keys
+(
+    saml_key_id          VARCHAR PRIMARY KEY,
+    saml_key_private_key BYTEA     NOT NULL,
+    saml_key_certificate BYTEA     NOT NULL,
+    saml_key_created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE saml_service_providers
+(
+    saml_service_provider_client_id  VARCHAR PRIMARY KEY REFERENCES clients (client_id) ON DELETE CASCADE,
+    saml_service_provider_entity_id  VARCHAR   NOT NULL UNIQUE,
+    saml_service_provider_acs_url    VARCHAR   NOT NULL,
+    saml_service_provider_metadata   VARCHAR   NOT NULL,
+    saml_service_provider_club_id    VARCHAR   NOT NULL,
+    saml_service_provider_channel_id VARCHAR   NOT NULL,
+    saml_service_provider_created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE logins
+    ADD COLUMN login_saml_request_id VARCHAR;
@@ -0,0 +1,14 @@
+-- lifetimes are in seconds, NULL uses the configured default
+ALTER TABLE clients
+    ADD COLUMN client_login_code_lifetime     INTEGER,
+    ADD COLUMN client_login_verified_lifetime INTEGER,
+    ADD COLUMN client_login_exchange_lifetime INTEGER;
+
+ALTER TABLE logins
+    ADD COLUMN login_expires_at TIMESTAMP;
+
+UPDATE logins
+SET login_expires_at = login_created_at + INTERVAL '240 seconds';
+
+ALTER TABLE logins
+    ALTER COLUMN login_expires_at SET NOT NULL;
@@ -0,0 +1,12 @@
+-- clients without any channel keep accepting logins in every channel
+CREATE TABLE client_channels
+(
+    client_channel_client_id  VARCHAR   NOT NULL REFERENCES clients (client_id) ON DELETE CASCADE,
+    client_channel_club_id    VARCHAR   NOT NULL,
+    client_channel_channel_id VARCHAR   NOT NULL,
+    client_channel_default    BOOLEAN   NOT NULL DEFAULT false,
+    client_channel_created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (client_channel_client_id, client_channel_club_id, client_channel_channel_id)
+);
+
+CREATE UNIQUE INDEX client_channels_default_idx ON client_channels (client_channel_client_id) WHERE client_channel_default;
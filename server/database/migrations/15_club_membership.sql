@@ -0,0 +1,7 @@
+-- client_min_club_role is NULL when any role is accepted
+ALTER TABLE clients
+    ADD COLUMN client_require_club_member BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN client_min_club_role       VARCHAR;
+
+ALTER TABLE logins
+    ADD COLUMN login_club_member JSONB;
@@ -0,0 +1,3 @@
+-- headless logins of the login API have no consent screen, posting the code already grants the requested scope
+ALTER TABLE logins
+    ADD COLUMN login_api BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,12 @@
+ALTER TABLE logins
+    ADD COLUMN login_message_id        VARCHAR,
+    ADD COLUMN login_message_sender_id VARCHAR,
+    ADD COLUMN login_verified_at       TIMESTAMP;
+
+-- a channel message can only verify a single login, the used messages are kept independently of the deleted logins
+CREATE TABLE login_messages
+(
+    login_message_id        VARCHAR PRIMARY KEY,
+    login_message_sender_id VARCHAR   NOT NULL,
+    login_message_used_at   TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+-- sessions let users who already verified a login continue without posting another code
+CREATE TABLE sessions
+(
+    session_id         BIGSERIAL PRIMARY KEY,
+    session_token      VARCHAR   NOT NULL UNIQUE,
+    session_user_id    VARCHAR   NOT NULL,
+    session_user       JSONB     NOT NULL,
+    session_expires_at TIMESTAMP NOT NULL,
+    session_created_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+-- grants remember which clients a user authorized and with which scope, so users can review and revoke them
+CREATE TABLE grants
+(
+    grant_client_id  VARCHAR   NOT NULL REFERENCES clients (client_id) ON DELETE CASCADE,
+    grant_user_id    VARCHAR   NOT NULL,
+    grant_scope      VARCHAR   NOT NULL DEFAULT '',
+    grant_created_at TIMESTAMP NOT NULL DEFAULT now(),
+    grant_updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (grant_client_id, grant_user_id)
+);
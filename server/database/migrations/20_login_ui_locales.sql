@@ -0,0 +1,3 @@
+-- the ui_locales of the login request, the login pages are rendered in the first supported language
+ALTER TABLE logins
+    ADD COLUMN login_ui_locales VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- branding shown on the login pages, client_logo_updated_at is NULL for clients without a logo
+ALTER TABLE clients
+    ADD COLUMN client_display_name    VARCHAR,
+    ADD COLUMN client_homepage_url    VARCHAR,
+    ADD COLUMN client_privacy_url     VARCHAR,
+    ADD COLUMN client_accent_color    VARCHAR,
+    ADD COLUMN client_logo_updated_at TIMESTAMP;
+
+-- logos are kept out of the clients table, so loading a client doesn't load its logo
+CREATE TABLE client_logos
+(
+    client_logo_client_id VARCHAR PRIMARY KEY REFERENCES clients (client_id) ON DELETE CASCADE,
+    client_logo_image     BYTEA NOT NULL
+);
@@ -0,0 +1,8 @@
+-- logins of clients with the mention verification mode are verified by the user entering the code the server mentioned them with
+ALTER TABLE clients
+    ADD COLUMN client_verification_mode VARCHAR NOT NULL DEFAULT 'channel_code';
+
+ALTER TABLE logins
+    ADD COLUMN login_mention          BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN login_mention_user_id  VARCHAR,
+    ADD COLUMN login_mention_attempts INT     NOT NULL DEFAULT 0;
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int        `db:"refresh_token_id"`
+	Token     string     `db:"refresh_token_token"`
+	ClientID  string     `db:"refresh_token_client_id"`
+	UserID    string     `db:"refresh_token_user_id"`
+	Scope     string     `db:"refresh_token_scope"`
+	ExpiresAt time.Time  `db:"refresh_token_expires_at"`
+	CreatedAt time.Time  `db:"refresh_token_created_at"`
+	RevokedAt *time.Time `db:"refresh_token_revoked_at"`
+	Family    string     `db:"refresh_token_family"`
+}
+
+func (d *Database) InsertRefreshToken(ctx context.Context, token RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (refresh_token_token, refresh_token_client_id, refresh_token_user_id, refresh_token_scope, refresh_token_expires_at, refresh_token_family)
+		VALUES (:refresh_token_token, :refresh_token_client_id, :refresh_token_user_id, :refresh_token_scope, :refresh_token_expires_at, :refresh_token_family)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRefreshTokenByClientIDToken consumes a refresh token.
+func (d *Database) DeleteRefreshTokenByClientIDToken(ctx context.Context, clientID string, token string) (*RefreshToken, error) {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE refresh_token_client_id = $1
+		AND refresh_token_token = $2
+		AND refresh_token_expires_at > now()
+		AND refresh_token_revoked_at IS NULL
+		RETURNING *
+	`
+
+	var refreshToken RefreshToken
+	if err := d.db.GetContext(ctx, &refreshToken, query, clientID, token); err != nil {
+		return nil, fmt.Errorf("failed to delete refresh token by client ID and token: %w", err)
+	}
+
+	return &refreshToken, nil
+}
+
+func (d *Database) GetRefreshToken(ctx context.Context, token string) (*RefreshToken, error) {
+	query := `
+		SELECT *
+		FROM refresh_tokens
+		WHERE refresh_token_token = $1
+		AND refresh_token_expires_at > now()
+		AND refresh_token_revoked_at IS NULL
+	`
+
+	var refreshToken RefreshToken
+	if err := d.db.GetContext(ctx, &refreshToken, query, token); err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &refreshToken, nil
+}
+
+func (d *Database) RevokeRefreshToken(ctx context.Context, clientID string, token string) (*RefreshToken, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET refresh_token_revoked_at = now()
+		WHERE refresh_token_client_id = $1
+		AND refresh_token_token = $2
+		AND refresh_token_revoked_at IS NULL
+		RETURNING *
+	`
+
+	var refreshToken RefreshToken
+	if err := d.db.GetContext(ctx, &refreshToken, query, clientID, token); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return &refreshToken, nil
+}
+
+func (d *Database) DeleteExpiredRefreshTokens(ctx context.Context) error {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE refresh_token_expires_at < now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+
+	return nil
+}
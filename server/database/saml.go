@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type SAMLKey struct {
+	ID          string    `db:"saml_key_id"`
+	PrivateKey  []byte    `db:"saml_key_private_key"`
+	Certificate []byte    `db:"saml_key_certificate"`
+	CreatedAt   time.Time `db:"saml_key_created_at"`
+}
+
+func (d *Database) InsertSAMLKey(ctx context.Context, key SAMLKey) error {
+	query := `
+		INSERT INTO saml_keys (saml_key_id, saml_key_private_key, saml_key_certificate, saml_key_created_at)
+		VALUES (:saml_key_id, :saml_key_private_key, :saml_key_certificate, :saml_key_created_at)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to insert saml key: %w", err)
+	}
+
+	return nil
+}
+
+// GetSAMLKey returns the newest SAML key.
+func (d *Database) GetSAMLKey(ctx context.Context) (*SAMLKey, error) {
+	query := `
+		SELECT *
+		FROM saml_keys
+		ORDER BY saml_key_created_at DESC
+		LIMIT 1
+	`
+
+	var key SAMLKey
+	if err := d.db.GetContext(ctx, &key, query); err != nil {
+		return nil, fmt.Errorf("failed to get saml key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// SAMLServiceProvider is the SAML service provider registered for a client.
+type SAMLServiceProvider struct {
+	ClientID  string    `db:"saml_service_provider_client_id"`
+	EntityID  string    `db:"saml_service_provider_entity_id"`
+	ACSURL    string    `db:"saml_service_provider_acs_url"`
+	Metadata  string    `db:"saml_service_provider_metadata"`
+	ClubID    string    `db:"saml_service_provider_club_id"`
+	ChannelID string    `db:"saml_service_provider_channel_id"`
+	CreatedAt time.Time `db:"saml_service_provider_created_at"`
+}
+
+func (d *Database) UpsertSAMLServiceProvider(ctx context.Context, sp SAMLServiceProvider) error {
+	query := `
+		INSERT INTO saml_service_providers (saml_service_provider_client_id, saml_service_provider_entity_id, saml_service_provider_acs_url, saml_service_provider_metadata, saml_service_provider_club_id, saml_service_provider_channel_id)
+		VALUES (:saml_service_provider_client_id, :saml_service_provider_entity_id, :saml_service_provider_acs_url, :saml_service_provider_metadata, :saml_service_provider_club_id, :saml_service_provider_channel_id)
+		ON CONFLICT (saml_service_provider_client_id) DO UPDATE
+		SET saml_service_provider_entity_id = excluded.saml_service_provider_entity_id,
+			saml_service_provider_acs_url = excluded.saml_service_provider_acs_url,
+			saml_service_provider_metadata = excluded.saml_service_provider_metadata,
+			saml_service_provider_club_id = excluded.saml_service_provider_club_id,
+			saml_service_provider_channel_id = excluded.saml_service_provider_channel_id
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, sp); err != nil {
+		return fmt.Errorf("failed to upsert saml service provider: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetSAMLServiceProvider(ctx context.Context, clientID string) (*SAMLServiceProvider, error) {
+	query := `
+		SELECT *
+		FROM saml_service_providers
+		WHERE saml_service_provider_client_id = $1
+	`
+
+	var sp SAMLServiceProvider
+	if err := d.db.GetContext(ctx, &sp, query, clientID); err != nil {
+		return nil, fmt.Errorf("failed to get saml service provider: %w", err)
+	}
+
+	return &sp, nil
+}
+
+func (d *Database) GetSAMLServiceProviderByEntityID(ctx context.Context, entityID string) (*SAMLServiceProvider, error) {
+	query := `
+		SELECT *
+		FROM saml_service_providers
+		WHERE saml_service_provider_entity_id = $1
+	`
+
+	var sp SAMLServiceProvider
+	if err := d.db.GetContext(ctx, &sp, query, entityID); err != nil {
+		return nil, fmt.Errorf("failed to get saml service provider by entity id: %w", err)
+	}
+
+	return &sp, nil
+}
+
+func (d *Database) DeleteSAMLServiceProvider(ctx context.Context, clientID string) error {
+	query := `
+		DELETE FROM saml_service_providers
+		WHERE saml_service_provider_client_id = $1
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, clientID); err != nil {
+		return fmt.Errorf("failed to delete saml service provider: %w", err)
+	}
+
+	return nil
+}
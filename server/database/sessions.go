@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Session remembers the user of a verified login in the browser.
+type Session struct {
+	ID        int             `db:"session_id"`
+	Token     string          `db:"session_token"`
+	UserID    string          `db:"session_user_id"`
+	User      json.RawMessage `db:"session_user"`
+	ExpiresAt time.Time       `db:"session_expires_at"`
+	CreatedAt time.Time       `db:"session_created_at"`
+}
+
+func (d *Database) InsertSession(ctx context.Context, session Session) error {
+	query := `
+		INSERT INTO sessions (session_token, session_user_id, session_user, session_expires_at)
+		VALUES (:session_token, :session_user_id, :session_user, :session_expires_at)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, session); err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetSession(ctx context.Context, token string) (*Session, error) {
+	query := `
+		SELECT *
+		FROM sessions
+		WHERE session_token = $1
+		AND session_expires_at > now()
+	`
+
+	var session Session
+	if err := d.db.GetContext(ctx, &session, query, token); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (d *Database) DeleteSession(ctx context.Context, token string) error {
+	query := `
+		DELETE FROM sessions
+		WHERE session_token = $1
+	`
+
+	if _, err := d.db.ExecContext(ctx, query, token); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteExpiredSessions(ctx context.Context) error {
+	query := `
+		DELETE FROM sessions
+		WHERE session_expires_at < now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return nil
+}
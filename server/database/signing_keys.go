@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type SigningKey struct {
+	ID         string    `db:"signing_key_id"`
+	Algorithm  string    `db:"signing_key_algorithm"`
+	PrivateKey []byte    `db:"signing_key_private_key"`
+	CreatedAt  time.Time `db:"signing_key_created_at"`
+	ExpiresAt  time.Time `db:"signing_key_expires_at"`
+}
+
+func (d *Database) InsertSigningKey(ctx context.Context, key SigningKey) error {
+	query := `
+		INSERT INTO signing_keys (signing_key_id, signing_key_algorithm, signing_key_private_key, signing_key_created_at, signing_key_expires_at)
+		VALUES (:signing_key_id, :signing_key_algorithm, :signing_key_private_key, :signing_key_created_at, :signing_key_expires_at)
+	`
+
+	if _, err := d.db.NamedExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to insert signing key: %w", err)
+	}
+
+	return nil
+}
+
+// GetSigningKeys returns all signing keys which have not expired yet, newest first.
+func (d *Database) GetSigningKeys(ctx context.Context) ([]SigningKey, error) {
+	query := `
+		SELECT *
+		FROM signing_keys
+		WHERE signing_key_expires_at > now()
+		ORDER BY signing_key_created_at DESC
+	`
+
+	var keys []SigningKey
+	if err := d.db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (d *Database) DeleteExpiredSigningKeys(ctx context.Context) error {
+	query := `
+		DELETE FROM signing_keys
+		WHERE signing_key_expires_at < now()
+	`
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete expired signing keys: %w", err)
+	}
+
+	return nil
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"cmp"
 	"html/template"
 	"time"
+
+	"github.com/topi314/campfire-auth/server/i18n"
 )
 
 var templateFuncs = template.FuncMap{
@@ -10,6 +13,26 @@ var templateFuncs = template.FuncMap{
 	"formatDateNice":         formatDateNice,
 	"formatTimeToDayTime":    formatTimeToDayTime,
 	"formatTimeToRelDayTime": formatTimeToRelDayTime,
+	"t":                      i18n.Translate,
+	"page":                   page,
+}
+
+// PageVars are the vars of the head template.
+type PageVars struct {
+	Title string
+	Lang  string
+}
+
+// page returns the vars of the head template, pages without a language are English.
+func page(title string, lang ...string) PageVars {
+	vars := PageVars{
+		Title: title,
+		Lang:  "en",
+	}
+	if len(lang) > 0 {
+		vars.Lang = cmp.Or(lang[0], vars.Lang)
+	}
+	return vars
 }
 
 func formatDate(t time.Time) string {
@@ -0,0 +1,79 @@
+// Package i18n translates the user facing pages into the languages of the embedded message catalogs.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.toml
+var locales embed.FS
+
+// Languages are the languages with a message catalog.
+var Languages = []language.Tag{
+	language.English,
+	language.German,
+	language.French,
+	language.Japanese,
+}
+
+var (
+	matcher  = language.NewMatcher(Languages)
+	catalogs = mustLoadCatalogs()
+)
+
+func mustLoadCatalogs() map[string]map[string]string {
+	c := make(map[string]map[string]string, len(Languages))
+	for _, lang := range Languages {
+		var catalog map[string]string
+		if _, err := toml.DecodeFS(locales, path.Join("locales", lang.String()+".toml"), &catalog); err != nil {
+			panic(fmt.Sprintf("failed to load %s message catalog: %s", lang, err))
+		}
+		c[lang.String()] = catalog
+	}
+	return c
+}
+
+// Match returns the best supported language for the ui_locales and the Accept-Language header.
+func Match(uiLocales string, acceptLanguage string) string {
+	var tags []language.Tag
+	for _, locale := range strings.Fields(uiLocales) {
+		if tag, err := language.Parse(locale); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	if accepted, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil {
+		tags = append(tags, accepted...)
+	}
+
+	_, index, _ := matcher.Match(tags...)
+	return Languages[index].String()
+}
+
+// Translate returns the message of the key in the given language formatted with the args.
+func Translate(lang string, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = catalogs[Languages[0].String()][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Supported returns the tags of all supported languages.
+func Supported() []string {
+	tags := make([]string, 0, len(Languages))
+	for _, lang := range Languages {
+		tags = append(tags, lang.String())
+	}
+	return tags
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogsHaveAllKeys(t *testing.T) {
+	en := catalogs[Languages[0].String()]
+	for _, lang := range Languages[1:] {
+		catalog := catalogs[lang.String()]
+		t.Run(lang.String(), func(t *testing.T) {
+			for key, msg := range en {
+				translated, ok := catalog[key]
+				if !ok {
+					t.Errorf("missing key %q", key)
+					continue
+				}
+				if got, want := strings.Count(translated, "%"), strings.Count(msg, "%"); got != want {
+					t.Errorf("key %q has %d format verbs, want %d", key, got, want)
+				}
+			}
+			for key := range catalog {
+				if _, ok := en[key]; !ok {
+					t.Errorf("unknown key %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		uiLocales      string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "no preference", want: "en"},
+		{name: "ui_locales", uiLocales: "de", want: "de"},
+		{name: "accept-language", acceptLanguage: "fr-FR,fr;q=0.9,en;q=0.8", want: "fr"},
+		{name: "ui_locales takes precedence", uiLocales: "ja", acceptLanguage: "de", want: "ja"},
+		{name: "first supported ui_locale", uiLocales: "xx es fr", want: "fr"},
+		{name: "region subtag", uiLocales: "de-AT", want: "de"},
+		{name: "unsupported language falls back to english", uiLocales: "es", acceptLanguage: "pt-BR", want: "en"},
+		{name: "invalid ui_locales are ignored", uiLocales: "!!", acceptLanguage: "ja", want: "ja"},
+		{name: "invalid accept-language is ignored", uiLocales: "fr", acceptLanguage: ";;;", want: "fr"},
+		{name: "accept-language quality", acceptLanguage: "de;q=0.5,ja;q=0.9", want: "ja"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.uiLocales, tt.acceptLanguage); got != tt.want {
+				t.Errorf("Match(%q, %q) = %q, want %q", tt.uiLocales, tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("en", "login.continue_as", "topi"); got != "Continue as @topi" {
+		t.Errorf("Translate() with args = %q, want %q", got, "Continue as @topi")
+	}
+	if got := Translate("xx", "login.button"); got != "Login" {
+		t.Errorf("Translate() with unknown language = %q, want english fallback", got)
+	}
+	if got := Translate("en", "unknown.key"); got != "unknown.key" {
+		t.Errorf("Translate() with unknown key = %q, want the key", got)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *Server) loginUpdateListener() {
+	for {
+		err := s.DB.ListenLoginUpdates(context.Background(), s.notifyLoginUpdate)
+		slog.Error("Stopped listening for login updates, reconnecting", slog.String("err", err.Error()))
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (s *Server) notifyLoginUpdate(loginID int) {
+	s.loginUpdatesMu.Lock()
+	defer s.loginUpdatesMu.Unlock()
+
+	for _, ch := range s.loginUpdates[loginID] {
+		// a pending signal is enough
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SubscribeLoginUpdates returns a channel which is signaled whenever the login got updated.
+func (s *Server) SubscribeLoginUpdates(loginID int) (<-chan struct{}, func()) {
+	s.loginUpdatesMu.Lock()
+	defer s.loginUpdatesMu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	s.loginUpdates[loginID] = append(s.loginUpdates[loginID], ch)
+
+	return ch, func() {
+		s.loginUpdatesMu.Lock()
+		defer s.loginUpdatesMu.Unlock()
+
+		subscribers := s.loginUpdates[loginID]
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				subscribers = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		if len(subscribers) == 0 {
+			delete(s.loginUpdates, loginID)
+			return
+		}
+		s.loginUpdates[loginID] = subscribers
+	}
+}
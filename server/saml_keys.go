@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+// samlCertificateLifetime is the validity of the self-signed SAML certificate.
+const samlCertificateLifetime = 10 * 365 * 24 * time.Hour
+
+type SAMLKey struct {
+	ID          string
+	Key         crypto.Signer
+	Certificate []byte
+}
+
+// SAMLKey returns the key SAML assertions are signed with and its certificate.
+func (s *Server) SAMLKey(ctx context.Context) (*SAMLKey, error) {
+	s.samlKeyMu.Lock()
+	defer s.samlKeyMu.Unlock()
+
+	dbKey, err := s.DB.GetSAMLKey(ctx)
+	if err == nil {
+		key, err := x509.ParsePKCS8PrivateKey(dbKey.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse saml key %s: %w", dbKey.ID, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("saml key %s is not a signer", dbKey.ID)
+		}
+		return &SAMLKey{
+			ID:          dbKey.ID,
+			Key:         signer,
+			Certificate: dbKey.Certificate,
+		}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	signer, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate saml key: %w", err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal saml key: %w", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate saml certificate serial number: %w", err)
+	}
+
+	commonName := s.Cfg.Server.PublicURL
+	if u, err := url.Parse(s.Cfg.Server.PublicURL); err == nil && u.Host != "" {
+		commonName = u.Hostname()
+	}
+
+	now := time.Now()
+	certificate, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"Campfire Auth"},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(samlCertificateLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}, &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"Campfire Auth"},
+		},
+	}, signer.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saml certificate: %w", err)
+	}
+
+	key := SAMLKey{
+		ID:          xrand.RandCharCode(),
+		Key:         signer,
+		Certificate: certificate,
+	}
+	if err = s.DB.InsertSAMLKey(ctx, database.SAMLKey{
+		ID:          key.ID,
+		PrivateKey:  privateKey,
+		Certificate: certificate,
+		CreatedAt:   now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
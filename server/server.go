@@ -14,6 +14,9 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
@@ -22,6 +25,7 @@ import (
 	"github.com/topi314/goreload"
 
 	"github.com/topi314/campfire-auth/internal/middlewares"
+	"github.com/topi314/campfire-auth/internal/xjwt"
 	"github.com/topi314/campfire-auth/server/campfire"
 	"github.com/topi314/campfire-auth/server/database"
 )
@@ -82,11 +86,21 @@ func New(cfg Config) (*Server, error) {
 		}
 	}
 
+	if cfg.OIDC.SigningAlgorithm != xjwt.AlgorithmRS256 && cfg.OIDC.SigningAlgorithm != xjwt.AlgorithmES256 {
+		return nil, fmt.Errorf("unsupported oidc signing algorithm: %s", cfg.OIDC.SigningAlgorithm)
+	}
+
 	db, err := database.New(cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	if cfg.Account.ClientID != "" {
+		if err = checkAccountClient(db, cfg); err != nil {
+			return nil, fmt.Errorf("invalid account config: %w", err)
+		}
+	}
+
 	var webhookClient *webhook.Client
 	if cfg.Notifications.Enabled {
 		webhookClient, err = webhook.NewWithURL(cfg.Notifications.WebhookURL)
@@ -120,11 +134,23 @@ func New(cfg Config) (*Server, error) {
 		WebhookClient: webhookClient,
 		Logo:          logoPNG,
 		Reloader:      reloader,
+		loginUpdates:  make(map[int][]chan struct{}),
+
+		readableChannels: make(map[string]readableChannel),
+	}
+
+	// relying parties might cache an empty JWKS
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = s.SigningKey(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create signing key: %w", err)
 	}
 
 	go s.cleanup()
 	go s.loginCodeChecker()
 	go s.loginCodeCleaner()
+	go s.loginUpdateListener()
+	go s.tokenCleaner()
 
 	return s, nil
 }
@@ -143,6 +169,27 @@ func getCampfireToken(db *database.Database) func(ctx context.Context) (string,
 	}
 }
 
+// checkAccountClient checks the client of the account page.
+func checkAccountClient(db *database.Database, cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := db.GetClient(ctx, cfg.Account.ClientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("client %s does not exist", cfg.Account.ClientID)
+		}
+		return fmt.Errorf("failed to get account client: %w", err)
+	}
+
+	redirectURI := strings.TrimSuffix(cfg.Server.PublicURL, "/") + "/account/callback"
+	if !slices.Contains(client.RedirectURIs.V, redirectURI) {
+		return fmt.Errorf("redirect URIs of client %s have to include %s", client.ID, redirectURI)
+	}
+
+	return nil
+}
+
 type Server struct {
 	Cfg                    Config
 	Server                 *http.Server
@@ -155,6 +202,15 @@ type Server struct {
 	SentTokenNotifications []int
 	Logo                   image.Image
 	Reloader               *goreload.Reloader
+
+	signingKeyMu sync.Mutex
+	samlKeyMu    sync.Mutex
+
+	loginUpdatesMu sync.Mutex
+	loginUpdates   map[int][]chan struct{}
+
+	readableChannelsMu sync.Mutex
+	readableChannels   map[string]readableChannel
 }
 
 func (s *Server) Start(handler http.Handler) {
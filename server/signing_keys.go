@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xjwt"
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.Signer
+	CreatedAt time.Time
+}
+
+// SigningKeys returns all published signing keys, newest first.
+func (s *Server) SigningKeys(ctx context.Context) ([]SigningKey, error) {
+	dbKeys, err := s.DB.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]SigningKey, 0, len(dbKeys))
+	for _, dbKey := range dbKeys {
+		key, err := x509.ParsePKCS8PrivateKey(dbKey.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", dbKey.ID, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s is not a signer", dbKey.ID)
+		}
+
+		keys = append(keys, SigningKey{
+			ID:        dbKey.ID,
+			Algorithm: dbKey.Algorithm,
+			Key:       signer,
+			CreatedAt: dbKey.CreatedAt,
+		})
+	}
+
+	return keys, nil
+}
+
+// SigningKey returns the key new tokens should be signed with.
+func (s *Server) SigningKey(ctx context.Context) (*SigningKey, error) {
+	s.signingKeyMu.Lock()
+	defer s.signingKeyMu.Unlock()
+
+	keys, err := s.SigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rotationInterval := time.Duration(s.Cfg.OIDC.KeyRotationInterval)
+	if len(keys) > 0 && keys[0].Algorithm == s.Cfg.OIDC.SigningAlgorithm && time.Since(keys[0].CreatedAt) < rotationInterval {
+		return &keys[0], nil
+	}
+
+	signer, err := xjwt.GenerateKey(s.Cfg.OIDC.SigningAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signing key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	key := SigningKey{
+		ID:        xrand.RandCharCode(),
+		Algorithm: s.Cfg.OIDC.SigningAlgorithm,
+		Key:       signer,
+		CreatedAt: now,
+	}
+	if err = s.DB.InsertSigningKey(ctx, database.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: privateKey,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(2 * rotationInterval),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *Server) tokenCleaner() {
+	for {
+		s.doTokenClean()
+		time.Sleep(1 * time.Minute)
+	}
+}
+
+func (s *Server) doTokenClean() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.DB.DeleteExpiredAccessTokens(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired access tokens", slog.String("err", err.Error()))
+	}
+
+	if err := s.DB.DeleteExpiredRefreshTokens(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired refresh tokens", slog.String("err", err.Error()))
+	}
+
+	if err := s.DB.DeleteExpiredSigningKeys(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired signing keys", slog.String("err", err.Error()))
+	}
+
+	if err := s.DB.DeleteExpiredInitialAccessTokens(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired initial access tokens", slog.String("err", err.Error()))
+	}
+
+	if err := s.DB.DeleteExpiredSessions(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired sessions", slog.String("err", err.Error()))
+	}
+}
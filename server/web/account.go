@@ -0,0 +1,217 @@
+package web
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+)
+
+const (
+	// accountLoginCookieName stores the state and code verifier of the login to the account page.
+	accountLoginCookieName = "campfire_auth_account_login"
+	accountLoginLifetime   = 1 * time.Hour
+)
+
+type AccountVars struct {
+	User   User
+	Grants []AccountGrant
+}
+
+type AccountGrant struct {
+	ClientID   string
+	ClientName string
+	Scopes     []Scope
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Account lists the clients the user of the session authorized.
+func (h *handler) Account(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Cfg.Account.ClientID == "" {
+		h.NotFound(w, r)
+		return
+	}
+
+	session, err := h.getSession(r)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get session", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		h.redirectAccountLogin(w, r)
+		return
+	}
+
+	user, err := newSessionUser(*session)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get session user", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	grants, err := h.DB.GetUserGrants(ctx, session.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user grants", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	accountGrants := make([]AccountGrant, 0, len(grants))
+	for _, grant := range grants {
+		accountGrants = append(accountGrants, AccountGrant{
+			ClientID:   grant.ClientID,
+			ClientName: grant.ClientName,
+			Scopes:     requestedScopes(grant.Scope),
+			CreatedAt:  grant.CreatedAt,
+			UpdatedAt:  grant.UpdatedAt,
+		})
+	}
+
+	if err = h.Templates().ExecuteTemplate(w, "account.gohtml", AccountVars{
+		User:   *user,
+		Grants: accountGrants,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to render account template", slog.String("err", err.Error()))
+	}
+}
+
+// redirectAccountLogin starts a login with the account client.
+func (h *handler) redirectAccountLogin(w http.ResponseWriter, r *http.Request) {
+	state := xrand.RandCharCode()
+	codeVerifier := xrand.RandSecret()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     accountLoginCookieName,
+		Value:    state + "." + codeVerifier,
+		Path:     "/account",
+		MaxAge:   int(accountLoginLifetime.Seconds()),
+		Secure:   strings.HasPrefix(h.Cfg.Server.PublicURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := url.Values{}
+	q.Set("client_id", h.Cfg.Account.ClientID)
+	q.Set("redirect_uri", h.accountRedirectURI())
+	q.Set("state", state)
+	q.Set("code_challenge", s256CodeChallenge(codeVerifier))
+	q.Set("code_challenge_method", codeChallengeMethodS256)
+
+	http.Redirect(w, r, "/login?"+q.Encode(), http.StatusFound)
+}
+
+// AccountCallback exchanges the code of the account client login and starts a session for the user.
+func (h *handler) AccountCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	if h.Cfg.Account.ClientID == "" {
+		h.NotFound(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(accountLoginCookieName)
+	if err != nil {
+		http.Error(w, "Missing account login, please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   accountLoginCookieName,
+		Path:   "/account",
+		MaxAge: -1,
+	})
+
+	state, codeVerifier, _ := strings.Cut(cookie.Value, ".")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.DB.DeleteLoginByClientIDExchangeCode(ctx, h.Cfg.Account.ClientID, query.Get("code"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Invalid code", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to delete account login", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if login.RedirectURI != h.accountRedirectURI() || !verifyCodeChallenge(login.CodeChallenge, login.CodeChallengeMethod, codeVerifier) {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.createSession(w, r, *login); err != nil {
+		slog.ErrorContext(ctx, "Failed to create session", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// AccountRevokeGrant revokes the grant of the client.
+func (h *handler) AccountRevokeGrant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.Cfg.Account.ClientID == "" {
+		h.NotFound(w, r)
+		return
+	}
+
+	session, err := h.getSession(r)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get session", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if err = h.DB.RevokeGrant(ctx, clientID, session.UserID); err != nil {
+		slog.ErrorContext(ctx, "Failed to revoke grant", slog.String("client_id", clientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "Revoked grant", slog.String("client_id", clientID), slog.String("user_id", session.UserID))
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// AccountLogout ends the session of the browser.
+func (h *handler) AccountLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if token, ok := h.sessionCookieToken(r); ok {
+		if err := h.DB.DeleteSession(ctx, token); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete session", slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookieName,
+		Path:   "/",
+		MaxAge: -1,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *handler) accountRedirectURI() string {
+	return strings.TrimSuffix(h.Cfg.Server.PublicURL, "/") + "/account/callback"
+}
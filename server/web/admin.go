@@ -1,25 +1,44 @@
 package web
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/topi314/campfire-auth/internal/xpgtype"
 	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/internal/xtime"
+	"github.com/topi314/campfire-auth/server/campfire"
 	"github.com/topi314/campfire-auth/server/database"
 )
 
 type AdminVars struct {
-	Tokens       []Token
-	Clients      []Client
-	Password     string
-	TokenErrors  []string
-	ClientErrors []string
+	Tokens                   []Token
+	InitialAccessTokens      []InitialAccessToken
+	Clients                  []Client
+	Password                 string
+	TokenErrors              []string
+	InitialAccessTokenErrors []string
+	ClientErrors             []string
+	ChannelURL               *ChannelURL
+	ChannelURLErrors         []string
+}
+
+// ChannelURL is a Campfire channel share link resolved into its club and channel IDs.
+type ChannelURL struct {
+	URL       string
+	ClubID    string
+	ChannelID string
+	// ReadErr is set if the channel can't be read by any Campfire token
+	ReadErr string
 }
 
 func newToken(token database.CampfireToken) Token {
@@ -38,14 +57,79 @@ type Token struct {
 	Email     string
 }
 
+func newInitialAccessToken(token database.InitialAccessToken) InitialAccessToken {
+	return InitialAccessToken{
+		ID:        token.ID,
+		Token:     token.Token,
+		Name:      token.Name,
+		ExpiresAt: token.ExpiresAt,
+	}
+}
+
+type InitialAccessToken struct {
+	ID        int
+	Token     string
+	Name      string
+	ExpiresAt time.Time
+}
+
 func newClient(client database.Client) Client {
+	var minClubRole string
+	if client.MinClubRole != nil {
+		minClubRole = *client.MinClubRole
+	}
+
 	return Client{
 		Name:         client.Name,
 		ID:           client.ID,
 		Secret:       client.Secret,
 		RedirectURIs: strings.Join(client.RedirectURIs.V, ", "),
+		Public:       client.Public,
+		Scopes:       client.Scopes.V,
 		CreatedAt:    client.CreatedAt,
+		Registered:   client.RegistrationAccessToken != nil,
+
+		LoginCodeLifetime:     formatLifetime(client.LoginCodeLifetime),
+		LoginVerifiedLifetime: formatLifetime(client.LoginVerifiedLifetime),
+		LoginExchangeLifetime: formatLifetime(client.LoginExchangeLifetime),
+
+		RequireClubMember: client.RequireClubMember,
+		MinClubRole:       minClubRole,
+		VerificationMode:  client.VerificationMode,
+
+		DisplayName: derefString(client.DisplayName),
+		HomepageURL: derefString(client.HomepageURL),
+		PrivacyURL:  derefString(client.PrivacyURL),
+		AccentColor: derefString(client.AccentColor),
+		LogoURL:     newClientBranding(client).LogoURL,
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatLifetime(lifetime *xtime.Duration) string {
+	if lifetime == nil {
+		return ""
+	}
+	return lifetime.String()
+}
+
+// parseLifetime parses an optional lifetime override like 4m30s.
+func parseLifetime(name string, value string) (*xtime.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var lifetime xtime.Duration
+	if err := lifetime.UnmarshalText([]byte(value)); err != nil || time.Duration(lifetime) < time.Second {
+		return nil, fmt.Errorf("%s must be a duration of at least one second like 4m30s", name)
 	}
+	lifetime = xtime.Duration(time.Duration(lifetime).Truncate(time.Second))
+	return &lifetime, nil
 }
 
 type Client struct {
@@ -53,14 +137,40 @@ type Client struct {
 	ID           string
 	Secret       string
 	RedirectURIs string
+	Public       bool
+	Scopes       []string
 	CreatedAt    time.Time
+	Registered   bool
+
+	LoginCodeLifetime     string
+	LoginVerifiedLifetime string
+	LoginExchangeLifetime string
+
+	RequireClubMember bool
+	MinClubRole       string
+	VerificationMode  string
+
+	DisplayName string
+	HomepageURL string
+	PrivacyURL  string
+	AccentColor string
+	LogoURL     string
 }
 
 func (h *handler) Admin(w http.ResponseWriter, r *http.Request) {
-	h.renderAdmin(w, r, nil, nil)
+	h.renderAdmin(w, r, adminErrors{})
+}
+
+type adminErrors struct {
+	tokens              []string
+	initialAccessTokens []string
+	clients             []string
+	channelURLs         []string
+	// channelURL is the result of the share link helper
+	channelURL *ChannelURL
 }
 
-func (h *handler) renderAdmin(w http.ResponseWriter, r *http.Request, tokenErrors []string, clientErrors []string) {
+func (h *handler) renderAdmin(w http.ResponseWriter, r *http.Request, errs adminErrors) {
 	ctx := r.Context()
 
 	if !h.checkIsAdmin(w, r) {
@@ -77,6 +187,16 @@ func (h *handler) renderAdmin(w http.ResponseWriter, r *http.Request, tokenError
 		tokenList = append(tokenList, newToken(t))
 	}
 
+	initialAccessTokens, err := h.DB.GetInitialAccessTokens(ctx)
+	if err != nil {
+		http.Error(w, "Failed to fetch initial access tokens: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var initialAccessTokenList []InitialAccessToken
+	for _, t := range initialAccessTokens {
+		initialAccessTokenList = append(initialAccessTokenList, newInitialAccessToken(t))
+	}
+
 	clients, err := h.DB.GetClients(ctx)
 	if err != nil {
 		http.Error(w, "Failed to fetch clients: "+err.Error(), http.StatusInternalServerError)
@@ -88,16 +208,52 @@ func (h *handler) renderAdmin(w http.ResponseWriter, r *http.Request, tokenError
 	}
 
 	if err = h.Templates().ExecuteTemplate(w, "admin.gohtml", AdminVars{
-		Tokens:       tokenList,
-		Clients:      clientList,
-		Password:     h.Cfg.Server.AdminPassword,
-		TokenErrors:  tokenErrors,
-		ClientErrors: clientErrors,
+		Tokens:                   tokenList,
+		InitialAccessTokens:      initialAccessTokenList,
+		Clients:                  clientList,
+		Password:                 h.Cfg.Server.AdminPassword,
+		TokenErrors:              errs.tokens,
+		InitialAccessTokenErrors: errs.initialAccessTokens,
+		ClientErrors:             errs.clients,
+		ChannelURL:               errs.channelURL,
+		ChannelURLErrors:         errs.channelURLs,
 	}); err != nil {
 		slog.ErrorContext(ctx, "Failed to render tracker template", slog.Any("err", err))
 	}
 }
 
+// AdminChannelURL resolves a pasted Campfire channel share link.
+func (h *handler) AdminChannelURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	channelURL := strings.TrimSpace(r.FormValue("channel_url"))
+	if channelURL == "" {
+		h.renderAdmin(w, r, adminErrors{channelURLs: []string{"Channel URL cannot be empty"}})
+		return
+	}
+
+	clubID, channelID, err := campfire.ResolveClubAndChannelID(channelURL)
+	if err != nil {
+		h.renderAdmin(w, r, adminErrors{channelURLs: []string{"Invalid channel URL: " + err.Error()}})
+		return
+	}
+
+	result := ChannelURL{
+		URL:       channelURL,
+		ClubID:    clubID,
+		ChannelID: channelID,
+	}
+	if err = h.CheckChannelReadable(ctx, channelID); err != nil {
+		result.ReadErr = err.Error()
+	}
+
+	h.renderAdmin(w, r, adminErrors{channelURL: &result})
+}
+
 func (h *handler) AdminTokens(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -107,18 +263,64 @@ func (h *handler) AdminTokens(w http.ResponseWriter, r *http.Request) {
 
 	token := r.FormValue("token")
 	if token == "" {
-		h.renderAdmin(w, r, []string{"Token cannot be empty"}, nil)
+		h.renderAdmin(w, r, adminErrors{tokens: []string{"Token cannot be empty"}})
 		return
 	}
 
 	campfireToken, err := parseToken(token)
 	if err != nil {
-		h.renderAdmin(w, r, []string{"Invalid token: " + err.Error()}, nil)
+		h.renderAdmin(w, r, adminErrors{tokens: []string{"Invalid token: " + err.Error()}})
 		return
 	}
 
 	if err = h.DB.InsertCampfireToken(ctx, *campfireToken); err != nil {
-		h.renderAdmin(w, r, []string{"Failed to insert token: " + err.Error()}, nil)
+		h.renderAdmin(w, r, adminErrors{tokens: []string{"Failed to insert token: " + err.Error()}})
+		return
+	}
+
+	h.redirectAdmin(w, r)
+}
+
+func (h *handler) AdminInitialAccessTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		h.renderAdmin(w, r, adminErrors{initialAccessTokens: []string{"Name cannot be empty"}})
+		return
+	}
+
+	if err := h.DB.InsertInitialAccessToken(ctx, database.InitialAccessToken{
+		Token:     xrand.RandSecret(),
+		Name:      name,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(h.Cfg.OAuth.InitialAccessTokenLifetime)),
+	}); err != nil {
+		h.renderAdmin(w, r, adminErrors{initialAccessTokens: []string{"Failed to insert initial access token: " + err.Error()}})
+		return
+	}
+
+	h.redirectAdmin(w, r)
+}
+
+func (h *handler) AdminDeleteInitialAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.renderAdmin(w, r, adminErrors{initialAccessTokens: []string{"Invalid initial access token ID"}})
+		return
+	}
+
+	if err = h.DB.DeleteInitialAccessToken(ctx, id); err != nil {
+		h.renderAdmin(w, r, adminErrors{initialAccessTokens: []string{"Failed to delete initial access token: " + err.Error()}})
 		return
 	}
 
@@ -135,31 +337,493 @@ func (h *handler) AdminClients(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	redirectURIs := r.FormValue("redirect_uris")
 	if name == "" {
-		h.renderAdmin(w, r, nil, []string{"Name cannot be empty"})
+		h.renderAdmin(w, r, adminErrors{clients: []string{"Name cannot be empty"}})
 		return
 	}
 	if redirectURIs == "" {
-		h.renderAdmin(w, r, nil, []string{"Redirect URIs cannot be empty"})
+		h.renderAdmin(w, r, adminErrors{clients: []string{"Redirect URIs cannot be empty"}})
 		return
 	}
 
-	var redirects []string
-	for _, uri := range strings.Split(redirectURIs, ",") {
-		uri = strings.TrimSpace(uri)
-		if uri != "" {
-			redirects = append(redirects, uri)
+	public := r.FormValue("public") == "on"
+
+	// public clients can't keep a secret, they have to use PKCE instead
+	var clientSecret string
+	if !public {
+		clientSecret = xrand.RandSecret()
+	}
+
+	if err := h.DB.InsertClient(ctx, database.Client{
+		ID:           xrand.RandCharCode(),
+		Name:         name,
+		Secret:       clientSecret,
+		RedirectURIs: xpgtype.NewJSON(parseRedirectURIs(redirectURIs)),
+		Public:       public,
+		Scopes:       xpgtype.NewJSON(allScopes()),
+	}); err != nil {
+		h.renderAdmin(w, r, adminErrors{clients: []string{"Failed to insert client: " + err.Error()}})
+		return
+	}
+
+	h.redirectAdmin(w, r)
+}
+
+type AdminClientVars struct {
+	Client        Client
+	Scopes        []AdminScope
+	Lifetimes     database.LoginLifetimes
+	Channels      []database.ClientChannel
+	SAML          *AdminSAMLServiceProvider
+	SAMLSSOURL    string
+	SAMLIdPURL    string
+	Password      string
+	Errors        []string
+	ChannelErrors []string
+	LogoErrors    []string
+	SAMLErrors    []string
+}
+
+type AdminSAMLServiceProvider struct {
+	EntityID  string
+	ACSURL    string
+	Metadata  string
+	ClubID    string
+	ChannelID string
+}
+
+type AdminScope struct {
+	Name    string
+	Enabled bool
+}
+
+func (h *handler) AdminClient(w http.ResponseWriter, r *http.Request) {
+	h.renderAdminClient(w, r, adminClientErrors{})
+}
+
+type adminClientErrors struct {
+	client   []string
+	channels []string
+	logo     []string
+	saml     []string
+}
+
+func (h *handler) renderAdminClient(w http.ResponseWriter, r *http.Request, errs adminClientErrors) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	client, err := h.DB.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.NotFound(w, r)
+			return
 		}
+		http.Error(w, "Failed to fetch client: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	clientID := xrand.RandCharCode()
-	clientSecret := xrand.RandCharCode()
+	var scopes []AdminScope
+	for _, scope := range allScopes() {
+		scopes = append(scopes, AdminScope{
+			Name:    scope,
+			Enabled: slices.Contains(client.Scopes.V, scope),
+		})
+	}
 
-	if err := h.DB.InsertClient(ctx, name, clientID, clientSecret, redirects); err != nil {
-		h.renderAdmin(w, r, nil, []string{"Failed to insert client: " + err.Error()})
+	channels, err := h.DB.GetClientChannels(ctx, clientID)
+	if err != nil {
+		http.Error(w, "Failed to fetch client channels: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.redirectAdmin(w, r)
+	var saml *AdminSAMLServiceProvider
+	sp, err := h.DB.GetSAMLServiceProvider(ctx, clientID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Failed to fetch saml service provider: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sp != nil {
+		saml = &AdminSAMLServiceProvider{
+			EntityID:  sp.EntityID,
+			ACSURL:    sp.ACSURL,
+			Metadata:  sp.Metadata,
+			ClubID:    sp.ClubID,
+			ChannelID: sp.ChannelID,
+		}
+	}
+
+	if err = h.Templates().ExecuteTemplate(w, "admin_client.gohtml", AdminClientVars{
+		Client:        newClient(*client),
+		Scopes:        scopes,
+		Lifetimes:     h.Cfg.Login.Lifetimes(),
+		Channels:      channels,
+		SAML:          saml,
+		SAMLSSOURL:    h.issuer() + "/saml/sso",
+		SAMLIdPURL:    h.samlEntityID(),
+		Password:      h.Cfg.Server.AdminPassword,
+		Errors:        errs.client,
+		ChannelErrors: errs.channels,
+		LogoErrors:    errs.logo,
+		SAMLErrors:    errs.saml,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to render admin client template", slog.Any("err", err))
+	}
+}
+
+func (h *handler) AdminUpdateClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	client, err := h.DB.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to fetch client: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err = r.ParseForm(); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{client: []string{"Invalid form: " + err.Error()}})
+		return
+	}
+
+	name := r.PostForm.Get("name")
+	redirectURIs := r.PostForm.Get("redirect_uris")
+	if name == "" {
+		h.renderAdminClient(w, r, adminClientErrors{client: []string{"Name cannot be empty"}})
+		return
+	}
+	if redirectURIs == "" {
+		h.renderAdminClient(w, r, adminClientErrors{client: []string{"Redirect URIs cannot be empty"}})
+		return
+	}
+
+	var scopes []string
+	for _, scope := range r.PostForm["scopes"] {
+		if slices.Contains(allScopes(), scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var lifetimeErrs []string
+	lifetimes := make([]*xtime.Duration, 3)
+	for i, name := range []string{"login_code_lifetime", "login_verified_lifetime", "login_exchange_lifetime"} {
+		if lifetimes[i], err = parseLifetime(name, r.PostForm.Get(name)); err != nil {
+			lifetimeErrs = append(lifetimeErrs, err.Error())
+		}
+	}
+	if len(lifetimeErrs) > 0 {
+		h.renderAdminClient(w, r, adminClientErrors{client: lifetimeErrs})
+		return
+	}
+
+	var minClubRole *string
+	if role := r.PostForm.Get("min_club_role"); role != "" {
+		if !slices.Contains(campfire.ClubRoles, campfire.ClubRole(role)) {
+			h.renderAdminClient(w, r, adminClientErrors{client: []string{"Invalid minimum club role"}})
+			return
+		}
+		minClubRole = &role
+	}
+
+	verificationMode := r.PostForm.Get("verification_mode")
+	if verificationMode != database.VerificationModeChannelCode && verificationMode != database.VerificationModeMention {
+		h.renderAdminClient(w, r, adminClientErrors{client: []string{"Invalid verification mode"}})
+		return
+	}
+
+	var brandingErrs []string
+	homepageURL, err := parseBrandingURL("Homepage URL", r.PostForm.Get("homepage_url"))
+	if err != nil {
+		brandingErrs = append(brandingErrs, err.Error())
+	}
+	privacyURL, err := parseBrandingURL("Privacy policy URL", r.PostForm.Get("privacy_url"))
+	if err != nil {
+		brandingErrs = append(brandingErrs, err.Error())
+	}
+	var accentColor *string
+	if color := r.PostForm.Get("accent_color"); color != "" {
+		if !accentColorRegex.MatchString(color) {
+			brandingErrs = append(brandingErrs, "Accent color must be a hex color like #007bff")
+		}
+		accentColor = &color
+	}
+	if len(brandingErrs) > 0 {
+		h.renderAdminClient(w, r, adminClientErrors{client: brandingErrs})
+		return
+	}
+	var displayName *string
+	if value := strings.TrimSpace(r.PostForm.Get("display_name")); value != "" {
+		displayName = &value
+	}
+
+	client.Name = name
+	client.RedirectURIs = xpgtype.NewJSON(parseRedirectURIs(redirectURIs))
+	client.Scopes = xpgtype.NewJSON(scopes)
+	client.LoginCodeLifetime = lifetimes[0]
+	client.LoginVerifiedLifetime = lifetimes[1]
+	client.LoginExchangeLifetime = lifetimes[2]
+	client.RequireClubMember = r.PostForm.Get("require_club_member") == "on"
+	client.MinClubRole = minClubRole
+	client.VerificationMode = verificationMode
+	client.DisplayName = displayName
+	client.HomepageURL = homepageURL
+	client.PrivacyURL = privacyURL
+	client.AccentColor = accentColor
+
+	if err = h.DB.UpdateClient(ctx, *client); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{client: []string{"Failed to update client: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", client.ID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+// AdminUpdateClientSAML registers the SAML service provider of a client from its metadata.
+func (h *handler) AdminUpdateClientSAML(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if _, err := h.DB.GetClient(ctx, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to fetch client: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Invalid form: " + err.Error()}})
+		return
+	}
+
+	metadata := strings.TrimSpace(r.PostForm.Get("metadata"))
+	if metadata == "" {
+		if err := h.DB.DeleteSAMLServiceProvider(ctx, clientID); err != nil {
+			h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Failed to delete saml service provider: " + err.Error()}})
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+		return
+	}
+
+	clubID := r.PostForm.Get("club_id")
+	channelID := r.PostForm.Get("channel_id")
+	// both empty uses the default channel of the client
+	if (clubID == "") != (channelID == "") {
+		h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Club ID and channel ID must both be set or both be empty"}})
+		return
+	}
+
+	if clubID != "" {
+		channels, err := h.DB.GetClientChannels(ctx, clientID)
+		if err != nil {
+			h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Failed to fetch client channels: " + err.Error()}})
+			return
+		}
+		if _, _, err = resolveChannel(channels, clubID, channelID); err != nil {
+			h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Invalid channel: " + err.Error()}})
+			return
+		}
+	}
+
+	entityID, acsURL, err := parseSAMLServiceProviderMetadata(metadata)
+	if err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Invalid metadata: " + err.Error()}})
+		return
+	}
+
+	if err = h.DB.UpsertSAMLServiceProvider(ctx, database.SAMLServiceProvider{
+		ClientID:  clientID,
+		EntityID:  entityID,
+		ACSURL:    acsURL,
+		Metadata:  metadata,
+		ClubID:    clubID,
+		ChannelID: channelID,
+	}); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{saml: []string{"Failed to save saml service provider: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+// AdminClientChannels allows a club channel for a client.
+func (h *handler) AdminClientChannels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if _, err := h.DB.GetClient(ctx, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to fetch client: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Invalid form: " + err.Error()}})
+		return
+	}
+
+	clubID := strings.TrimSpace(r.PostForm.Get("club_id"))
+	channelID := strings.TrimSpace(r.PostForm.Get("channel_id"))
+	clubID, channelID, err := resolveChannelURL(strings.TrimSpace(r.PostForm.Get("channel_url")), clubID, channelID)
+	if err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Invalid channel URL: " + err.Error()}})
+		return
+	}
+	if clubID == "" || channelID == "" {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Club ID and channel ID or channel URL cannot be empty"}})
+		return
+	}
+
+	if err := h.DB.InsertClientChannel(ctx, database.ClientChannel{
+		ClientID:  clientID,
+		ClubID:    clubID,
+		ChannelID: channelID,
+		Default:   r.PostForm.Get("default") == "on",
+	}); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Failed to add channel: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+// AdminDefaultClientChannel makes an allowed channel the default channel of a client.
+func (h *handler) AdminDefaultClientChannel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if err := r.ParseForm(); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Invalid form: " + err.Error()}})
+		return
+	}
+
+	clubID := r.PostForm.Get("club_id")
+	channelID := r.PostForm.Get("channel_id")
+	channels, err := h.DB.GetClientChannels(ctx, clientID)
+	if err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Failed to fetch client channels: " + err.Error()}})
+		return
+	}
+	if !slices.ContainsFunc(channels, func(channel database.ClientChannel) bool {
+		return channel.ClubID == clubID && channel.ChannelID == channelID
+	}) {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Channel is not allowed for this client"}})
+		return
+	}
+
+	if err = h.DB.UpdateClientDefaultChannel(ctx, clientID, clubID, channelID); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Failed to update default channel: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+// AdminDeleteClientChannel removes an allowed channel from a client.
+func (h *handler) AdminDeleteClientChannel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if err := r.ParseForm(); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Invalid form: " + err.Error()}})
+		return
+	}
+
+	if err := h.DB.DeleteClientChannel(ctx, clientID, r.PostForm.Get("club_id"), r.PostForm.Get("channel_id")); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{channels: []string{"Failed to delete channel: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+// AdminUpdateClientLogo uploads the logo of a client shown on the login pages and in the QR code.
+func (h *handler) AdminUpdateClientLogo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	r.Body = http.MaxBytesReader(w, r.Body, maxClientLogoUploadSize)
+	file, _, err := r.FormFile("logo")
+	if err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{logo: []string{"Invalid logo: " + err.Error()}})
+		return
+	}
+	defer file.Close()
+
+	logo, err := normalizeClientLogo(file)
+	if err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{logo: []string{err.Error()}})
+		return
+	}
+
+	if err = h.DB.UpdateClientLogo(ctx, clientID, logo); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{logo: []string{"Failed to update logo: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+func (h *handler) AdminDeleteClientLogo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !h.checkIsAdmin(w, r) {
+		return
+	}
+
+	clientID := r.PathValue("client_id")
+	if err := h.DB.DeleteClientLogo(ctx, clientID); err != nil {
+		h.renderAdminClient(w, r, adminClientErrors{logo: []string{"Failed to delete logo: " + err.Error()}})
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/clients/%s?password=%s", clientID, h.Cfg.Server.AdminPassword), http.StatusSeeOther)
+}
+
+func parseRedirectURIs(redirectURIs string) []string {
+	var redirects []string
+	for _, uri := range strings.Split(redirectURIs, ",") {
+		uri = strings.TrimSpace(uri)
+		if uri != "" {
+			redirects = append(redirects, uri)
+		}
+	}
+	return redirects
 }
 
 func (h *handler) redirectAdmin(w http.ResponseWriter, r *http.Request) {
@@ -185,7 +849,7 @@ func parseToken(token string) (*database.CampfireToken, error) {
 
 	return &database.CampfireToken{
 		Token:     token,
-		ExpiresAt: time.Unix(t.Exp, 0),
+		ExpiresAt: time.Unix(t.Exp, 0).UTC(),
 		Email:     t.Email,
 	}, nil
 }
@@ -6,6 +6,10 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
 )
 
 func (h *handler) ExchangeCode(w http.ResponseWriter, r *http.Request) {
@@ -24,8 +28,33 @@ func (h *handler) ExchangeCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	login, err := h.DB.DeleteLoginByClientIDSecretExchangeCode(ctx, username, password, code)
+	if _, err := h.DB.GetClientByIDSecret(ctx, username, password); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Invalid code", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get client", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	login, err := h.DB.GetLoginByClientIDExchangeCode(ctx, username, code)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Invalid code", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get login by exchange code", slog.String("code", code), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if login.CodeChallenge != "" && !verifyCodeChallenge(login.CodeChallenge, login.CodeChallengeMethod, query.Get("code_verifier")) {
+		http.Error(w, "Invalid code_verifier", http.StatusBadRequest)
+		return
+	}
+
+	if login, err = h.DB.DeleteLoginByClientIDSecretExchangeCode(ctx, username, password, code); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Invalid code", http.StatusBadRequest)
 			return
@@ -35,8 +64,38 @@ func (h *handler) ExchangeCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = w.Write(*login.User); err != nil {
-		slog.ErrorContext(ctx, "Failed to write login user", slog.String("err", err.Error()))
+	var user campfire.User
+	if err = json.Unmarshal(*login.User, &user); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal login user", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	clubMember, err := loginClubMember(*login)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal login club member", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.DB.UpsertGrant(ctx, login.ClientID, user.ID, *login.GrantedScope); err != nil {
+		slog.ErrorContext(ctx, "Failed to upsert grant", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(struct {
+		ScopedUser
+		ClubMember *campfire.ClubMember `json:"clubMember,omitempty"`
+		MessageID  *string              `json:"messageId,omitempty"`
+		VerifiedAt *time.Time           `json:"verifiedAt,omitempty"`
+	}{
+		ScopedUser: newScopedUser(user, *login.GrantedScope),
+		ClubMember: clubMember,
+		MessageID:  login.MessageID,
+		VerifiedAt: login.VerifiedAt,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to encode login user", slog.String("err", err.Error()))
 		return
 	}
 }
@@ -44,7 +103,7 @@ func (h *handler) ExchangeCode(w http.ResponseWriter, r *http.Request) {
 func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	if !h.checkClientAuth(w, r) {
+	if !h.checkUserAPIAuth(w, r) {
 		return
 	}
 
@@ -70,7 +129,7 @@ func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
 func (h *handler) SearchUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	query := r.URL.Query()
-	if !h.checkClientAuth(w, r) {
+	if !h.checkUserAPIAuth(w, r) {
 		return
 	}
 
@@ -93,24 +152,45 @@ func (h *handler) SearchUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *handler) checkClientAuth(w http.ResponseWriter, r *http.Request) bool {
+// checkUserAPIAuth authenticates a request to the user API.
+func (h *handler) checkUserAPIAuth(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := bearerToken(r); !ok {
+		_, ok = h.checkClientAuth(w, r)
+		return ok
+	}
+
+	accessToken, ok := h.authenticateBearer(w, r)
+	if !ok {
+		return false
+	}
+
+	if accessToken.UserID != "" || !hasScope(accessToken.Scope, scopeUsersRead) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="insufficient_scope", scope="users:read"`)
+		http.Error(w, "Insufficient scope", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (h *handler) checkClientAuth(w http.ResponseWriter, r *http.Request) (*database.Client, bool) {
 	ctx := r.Context()
 	username, password, ok := r.BasicAuth()
 	if !ok {
 		http.Error(w, "Missing basic auth", http.StatusUnauthorized)
-		return false
+		return nil, false
 	}
 
-	_, err := h.DB.GetClientByIDSecret(ctx, username, password)
+	client, err := h.DB.GetClientByIDSecret(ctx, username, password)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Invalid client credentials", http.StatusUnauthorized)
-			return false
+			return nil, false
 		}
 		slog.ErrorContext(ctx, "Failed to get client by ID and secret", slog.String("client_id", username), slog.String("err", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return false
+		return nil, false
 	}
 
-	return true
+	return client, true
 }
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"golang.org/x/image/draw"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	// clientLogoSize is the maximum width and height of client logos.
+	clientLogoSize = 128
+	// maxClientLogoUploadSize is the maximum size of uploaded logo files.
+	maxClientLogoUploadSize = 2 << 20
+	// maxClientLogoDimension is the maximum width and height of uploaded logos.
+	maxClientLogoDimension = 4096
+)
+
+var accentColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ClientBranding is shown on the login pages.
+type ClientBranding struct {
+	Name        string
+	LogoURL     string
+	HomepageURL string
+	PrivacyURL  string
+	AccentColor string
+}
+
+func newClientBranding(client database.Client) *ClientBranding {
+	branding := ClientBranding{
+		Name: client.Name,
+	}
+	if client.DisplayName != nil {
+		branding.Name = *client.DisplayName
+	}
+	if client.LogoUpdatedAt != nil {
+		branding.LogoURL = fmt.Sprintf("/clients/%s/logo?v=%d", url.PathEscape(client.ID), client.LogoUpdatedAt.Unix())
+	}
+	if client.HomepageURL != nil {
+		branding.HomepageURL = *client.HomepageURL
+	}
+	if client.PrivacyURL != nil {
+		branding.PrivacyURL = *client.PrivacyURL
+	}
+	if client.AccentColor != nil {
+		branding.AccentColor = *client.AccentColor
+	}
+	return &branding
+}
+
+// getClientBranding returns the branding of the client, nil if the client does not exist.
+func (h *handler) getClientBranding(ctx context.Context, clientID string) (*ClientBranding, error) {
+	client, err := h.DB.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return newClientBranding(*client), nil
+}
+
+// ClientLogo serves the logo of a client.
+func (h *handler) ClientLogo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	clientID := r.PathValue("client_id")
+	logo, err := h.DB.GetClientLogo(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.NotFound(w, r)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get client logo", slog.String("client_id", clientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(logo)
+}
+
+// loginLogo returns the logo shown in the center of the login QR code.
+func (h *handler) loginLogo(ctx context.Context, login database.Login) image.Image {
+	client, err := h.DB.GetClient(ctx, login.ClientID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get login client", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		return h.Logo
+	}
+	if client.LogoUpdatedAt == nil {
+		return h.Logo
+	}
+
+	logo, err := h.DB.GetClientLogo(ctx, client.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client logo", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		return h.Logo
+	}
+	img, err := png.Decode(bytes.NewReader(logo))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to decode client logo", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		return h.Logo
+	}
+	return img
+}
+
+// normalizeClientLogo scales the uploaded logo down to clientLogoSize and encodes it as PNG.
+func normalizeClientLogo(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logo: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("logo must be a PNG, JPEG or GIF image: %w", err)
+	}
+	if cfg.Width > maxClientLogoDimension || cfg.Height > maxClientLogoDimension {
+		return nil, fmt.Errorf("logo must not be larger than %dx%d pixels", maxClientLogoDimension, maxClientLogoDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("logo must be a PNG, JPEG or GIF image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > clientLogoSize || height > clientLogoSize {
+		if width >= height {
+			width, height = clientLogoSize, max(1, height*clientLogoSize/width)
+		} else {
+			width, height = max(1, width*clientLogoSize/height), clientLogoSize
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+		img = scaled
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode logo: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseBrandingURL parses an optional homepage or privacy policy URL.
+func parseBrandingURL(name string, value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%s must be an absolute http or https URL", name)
+	}
+	return &value, nil
+}
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestNormalizeClientLogo(t *testing.T) {
+	encode := func(t *testing.T, width int, height int, format string) []byte {
+		t.Helper()
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for x := range width {
+			img.Set(x, 0, color.RGBA{R: 255, A: 255})
+		}
+
+		var buf bytes.Buffer
+		var err error
+		switch format {
+		case "png":
+			err = png.Encode(&buf, img)
+		case "jpeg":
+			err = jpeg.Encode(&buf, img, nil)
+		case "gif":
+			err = gif.Encode(&buf, img, nil)
+		}
+		if err != nil {
+			t.Fatalf("failed to encode %s: %s", format, err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		data       func(t *testing.T) []byte
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{name: "small png is kept", data: func(t *testing.T) []byte { return encode(t, 64, 32, "png") }, wantWidth: 64, wantHeight: 32},
+		{name: "exact size png", data: func(t *testing.T) []byte { return encode(t, clientLogoSize, clientLogoSize, "png") }, wantWidth: clientLogoSize, wantHeight: clientLogoSize},
+		{name: "wide png is scaled down", data: func(t *testing.T) []byte { return encode(t, 512, 256, "png") }, wantWidth: clientLogoSize, wantHeight: clientLogoSize / 2},
+		{name: "tall png is scaled down", data: func(t *testing.T) []byte { return encode(t, 256, 512, "png") }, wantWidth: clientLogoSize / 2, wantHeight: clientLogoSize},
+		{name: "thin png keeps at least one pixel", data: func(t *testing.T) []byte { return encode(t, 1024, 1, "png") }, wantWidth: clientLogoSize, wantHeight: 1},
+		{name: "jpeg", data: func(t *testing.T) []byte { return encode(t, 256, 256, "jpeg") }, wantWidth: clientLogoSize, wantHeight: clientLogoSize},
+		{name: "gif", data: func(t *testing.T) []byte { return encode(t, 32, 32, "gif") }, wantWidth: 32, wantHeight: 32},
+		{name: "maximum dimension", data: func(t *testing.T) []byte { return encode(t, maxClientLogoDimension, 1, "png") }, wantWidth: clientLogoSize, wantHeight: 1},
+		{name: "too wide", data: func(t *testing.T) []byte { return encode(t, maxClientLogoDimension+1, 1, "png") }, wantErr: true},
+		{name: "too tall", data: func(t *testing.T) []byte { return encode(t, 1, maxClientLogoDimension+1, "png") }, wantErr: true},
+		{name: "not an image", data: func(t *testing.T) []byte { return []byte("not an image") }, wantErr: true},
+		{name: "empty", data: func(t *testing.T) []byte { return nil }, wantErr: true},
+		{name: "truncated png", data: func(t *testing.T) []byte { return encode(t, 64, 64, "png")[:40] }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logo, err := normalizeClientLogo(bytes.NewReader(tt.data(t)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeClientLogo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			cfg, err := png.DecodeConfig(bytes.NewReader(logo))
+			if err != nil {
+				t.Fatalf("normalized logo is not a png: %s", err)
+			}
+			if cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+				t.Errorf("normalized logo is %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"errors"
+
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+var (
+	errChannelURLWithIDs = errors.New("channel_url can't be combined with club_id or channel_id")
+	errNoDefaultChannel  = errors.New("missing club_id and channel_id, the client has no default channel")
+	errMissingClubID     = errors.New("missing club_id")
+	errMissingChannelID  = errors.New("missing channel_id")
+	errChannelNotAllowed = errors.New("channel is not allowed for this client")
+)
+
+// resolveChannelURL resolves the club and channel IDs of a Campfire channel share link.
+func resolveChannelURL(channelURL string, clubID string, channelID string) (string, string, error) {
+	if channelURL == "" {
+		return clubID, channelID, nil
+	}
+	if clubID != "" || channelID != "" {
+		return "", "", errChannelURLWithIDs
+	}
+	return campfire.ResolveClubAndChannelID(channelURL)
+}
+
+// resolveChannel validates the requested club and channel against the channels of the client.
+func resolveChannel(channels []database.ClientChannel, clubID string, channelID string) (string, string, error) {
+	if clubID == "" && channelID == "" {
+		for _, channel := range channels {
+			if channel.Default {
+				return channel.ClubID, channel.ChannelID, nil
+			}
+		}
+		return "", "", errNoDefaultChannel
+	}
+	if clubID == "" {
+		return "", "", errMissingClubID
+	}
+	if channelID == "" {
+		return "", "", errMissingChannelID
+	}
+
+	if len(channels) == 0 {
+		return clubID, channelID, nil
+	}
+	for _, channel := range channels {
+		if channel.ClubID == clubID && channel.ChannelID == channelID {
+			return clubID, channelID, nil
+		}
+	}
+	return "", "", errChannelNotAllowed
+}
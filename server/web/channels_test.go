@@ -0,0 +1,92 @@
+package web
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+func TestResolveChannel(t *testing.T) {
+	channels := []database.ClientChannel{
+		{ClubID: "club1", ChannelID: "channel1"},
+		{ClubID: "club1", ChannelID: "channel2", Default: true},
+		{ClubID: "club2", ChannelID: "channel3"},
+	}
+
+	tests := []struct {
+		name          string
+		channels      []database.ClientChannel
+		clubID        string
+		channelID     string
+		wantClubID    string
+		wantChannelID string
+		wantErr       bool
+	}{
+		{name: "allowed channel", channels: channels, clubID: "club1", channelID: "channel1", wantClubID: "club1", wantChannelID: "channel1"},
+		{name: "default channel", channels: channels, wantClubID: "club1", wantChannelID: "channel2"},
+		{name: "no default channel", channels: channels[:1], wantErr: true},
+		{name: "no channels and no ids", channels: nil, wantErr: true},
+		{name: "missing club id", channels: channels, channelID: "channel1", wantErr: true},
+		{name: "missing channel id", channels: channels, clubID: "club1", wantErr: true},
+		{name: "channel of another club", channels: channels, clubID: "club2", channelID: "channel1", wantErr: true},
+		{name: "unknown channel", channels: channels, clubID: "club3", channelID: "channel4", wantErr: true},
+		{name: "client without channels accepts every channel", channels: nil, clubID: "club3", channelID: "channel4", wantClubID: "club3", wantChannelID: "channel4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID, channelID, err := resolveChannel(tt.channels, tt.clubID, tt.channelID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveChannel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if clubID != tt.wantClubID || channelID != tt.wantChannelID {
+				t.Errorf("resolveChannel() = %q, %q, want %q, %q", clubID, channelID, tt.wantClubID, tt.wantChannelID)
+			}
+		})
+	}
+}
+
+func TestResolveChannelURL(t *testing.T) {
+	shareLink := "https://campfire.onelink.me/eBr8?af_dp=campfire%3A%2F%2F&af_force_deeplink=true&deep_link_sub1=" + base64.StdEncoding.EncodeToString([]byte("r=clubs&c=club1&ch=channel1"))
+
+	tests := []struct {
+		name          string
+		channelURL    string
+		clubID        string
+		channelID     string
+		wantClubID    string
+		wantChannelID string
+		wantErr       error
+		wantAnyErr    bool
+	}{
+		{name: "no channel url keeps the ids", clubID: "club2", channelID: "channel2", wantClubID: "club2", wantChannelID: "channel2"},
+		{name: "no channel url and no ids", wantClubID: "", wantChannelID: ""},
+		{name: "share link", channelURL: shareLink, wantClubID: "club1", wantChannelID: "channel1"},
+		{name: "share link with club id", channelURL: shareLink, clubID: "club2", wantErr: errChannelURLWithIDs},
+		{name: "share link with channel id", channelURL: shareLink, channelID: "channel2", wantErr: errChannelURLWithIDs},
+		{name: "link without deep link", channelURL: "https://campfire.onelink.me/eBr8", wantAnyErr: true},
+		{name: "invalid deep link", channelURL: "https://campfire.onelink.me/eBr8?deep_link_sub1=%25%25", wantAnyErr: true},
+		{name: "deep link to a profile", channelURL: "https://campfire.onelink.me/eBr8?deep_link_sub1=" + base64.StdEncoding.EncodeToString([]byte("r=profile&c=club1")), wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clubID, channelID, err := resolveChannelURL(tt.channelURL, tt.clubID, tt.channelID)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveChannelURL() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil {
+					t.Fatal("resolveChannelURL() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveChannelURL() error = %v", err)
+			}
+			if clubID != tt.wantClubID || channelID != tt.wantChannelID {
+				t.Errorf("resolveChannelURL() = %q, %q, want %q, %q", clubID, channelID, tt.wantClubID, tt.wantChannelID)
+			}
+		})
+	}
+}
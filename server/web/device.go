@@ -0,0 +1,147 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+// devicePollInterval is the minimum time a device has to wait between polling the token endpoint.
+const devicePollInterval = 5 * time.Second
+
+// OAuth 2.0 device authorization grant error codes as defined in RFC 8628 section 3.5.
+const (
+	oauthErrAuthorizationPending = "authorization_pending"
+	oauthErrSlowDown             = "slow_down"
+	oauthErrExpiredToken         = "expired_token"
+)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorization implements the device authorization endpoint as described in RFC 8628.
+func (h *handler) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	clubID := r.PostForm.Get("club_id")
+	channelID := r.PostForm.Get("channel_id")
+	scope, err := resolveScope(*client, r.PostForm.Get("scope"))
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidScope, err.Error())
+		return
+	}
+	channels, err := h.DB.GetClientChannels(ctx, client.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client channels", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+	clubID, channelID, err = resolveChannel(channels, clubID, channelID)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid channel: "+err.Error())
+		return
+	}
+	if err = h.CheckChannelReadable(ctx, channelID); err != nil {
+		slog.WarnContext(ctx, "Device login channel can't be read", slog.String("client_id", client.ID), slog.String("channel_id", channelID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Channel can't be read by campfire-auth, codes posted in it could not be verified")
+		return
+	}
+
+	lifetimes := h.Cfg.Login.Lifetimes().ForClient(*client)
+	login, err := h.insertLogin(ctx, database.Login{
+		ClientID:  client.ID,
+		ClubID:    clubID,
+		ChannelID: channelID,
+		Scope:     scope,
+		Device:    true,
+	}, lifetimes.Code)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to insert device login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              login.ExchangeCode,
+		UserCode:                loginMessage(login.Code, h.Cfg.Login.CodePrefix),
+		VerificationURI:         getChannelLink(clubID, channelID),
+		VerificationURIComplete: fmt.Sprintf("%s/login/re/%s", h.Cfg.Server.PublicURL, login.Code),
+		ExpiresIn:               int(lifetimes.Code.Seconds()),
+		Interval:                int(devicePollInterval.Seconds()),
+	})
+}
+
+func (h *handler) tokenDeviceCode(w http.ResponseWriter, r *http.Request, client *database.Client) {
+	ctx := r.Context()
+
+	deviceCode := r.PostForm.Get("device_code")
+	if deviceCode == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing device_code")
+		return
+	}
+
+	login, err := h.DB.GetDeviceLoginByClientIDExchangeCode(ctx, client.ID, deviceCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid device_code")
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get device login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	if time.Now().After(login.ExpiresAt) {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrExpiredToken, "The device_code has expired")
+		return
+	}
+
+	if login.User == nil {
+		if err = h.DB.UpdateLoginDevicePolledAt(ctx, login.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to update device login polled at", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+
+		if login.DevicePolledAt != nil && time.Since(*login.DevicePolledAt) < devicePollInterval {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrSlowDown, "")
+			return
+		}
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrAuthorizationPending, "")
+		return
+	}
+
+	login, err = h.DB.DeleteLoginByClientIDExchangeCode(ctx, client.ID, deviceCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid device_code")
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to delete device login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeLoginTokenResponse(w, r, client, *login)
+}
@@ -0,0 +1,168 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	tokenTypeHintAccessToken  = "access_token"
+	tokenTypeHintRefreshToken = "refresh_token"
+)
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// Introspect implements token introspection as described in RFC 7662.
+func (h *handler) Introspect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	// public clients can't keep a secret
+	if client.Public {
+		h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Public clients can't introspect tokens")
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing token")
+		return
+	}
+
+	// the hint only decides which kind of token is looked up first
+	lookups := []func() (*introspectionResponse, error){
+		func() (*introspectionResponse, error) {
+			accessToken, err := h.DB.GetAccessToken(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+			if accessToken.ClientID != client.ID {
+				return &introspectionResponse{Active: false}, nil
+			}
+			return &introspectionResponse{
+				Active:    true,
+				Scope:     accessToken.Scope,
+				ClientID:  accessToken.ClientID,
+				Subject:   accessToken.UserID,
+				TokenType: tokenTypeBearer,
+				ExpiresAt: accessToken.ExpiresAt.Unix(),
+				IssuedAt:  accessToken.CreatedAt.Unix(),
+				Issuer:    h.issuer(),
+			}, nil
+		},
+		func() (*introspectionResponse, error) {
+			refreshToken, err := h.DB.GetRefreshToken(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+			if refreshToken.ClientID != client.ID {
+				return &introspectionResponse{Active: false}, nil
+			}
+			return &introspectionResponse{
+				Active:    true,
+				Scope:     refreshToken.Scope,
+				ClientID:  refreshToken.ClientID,
+				Subject:   refreshToken.UserID,
+				ExpiresAt: refreshToken.ExpiresAt.Unix(),
+				IssuedAt:  refreshToken.CreatedAt.Unix(),
+				Issuer:    h.issuer(),
+			}, nil
+		},
+	}
+	if r.PostFormValue("token_type_hint") == tokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		rs, err := lookup()
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			slog.ErrorContext(ctx, "Failed to introspect token", slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+
+		h.writeOAuthJSON(w, r, http.StatusOK, rs)
+		return
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusOK, introspectionResponse{Active: false})
+}
+
+// Revoke implements token revocation as described in RFC 7009.
+func (h *handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing token")
+		return
+	}
+
+	revokers := []func() (bool, error){
+		func() (bool, error) {
+			rows, err := h.DB.RevokeAccessToken(ctx, client.ID, token)
+			return rows > 0, err
+		},
+		func() (bool, error) {
+			refreshToken, err := h.DB.RevokeRefreshToken(ctx, client.ID, token)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return false, nil
+				}
+				return false, err
+			}
+			return true, h.DB.RevokeAccessTokensByFamily(ctx, client.ID, refreshToken.Family)
+		},
+	}
+	if r.PostFormValue("token_type_hint") == tokenTypeHintRefreshToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	// invalid tokens do not cause an error response, see RFC 7009 section 2.2
+	for _, revoke := range revokers {
+		revoked, err := revoke()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to revoke token", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+		if revoked {
+			break
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"cmp"
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
@@ -11,161 +12,292 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
-	"slices"
+	"strings"
+	"time"
 
 	"github.com/yeqown/go-qrcode/v2"
 	"github.com/yeqown/go-qrcode/writer/standard"
 
 	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server"
 	"github.com/topi314/campfire-auth/server/database"
+	"github.com/topi314/campfire-auth/server/i18n"
 )
 
 type LoginVars struct {
-	ClientID    string
-	RedirectURI string
-	ClubID      string
-	ChannelID   string
-	State       string
-	Errs        []string
+	ClientID            string
+	RedirectURI         string
+	ClubID              string
+	ChannelID           string
+	State               string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	SAMLRequestID       string
+	UILocales           string
+	Lang                string
+	Client              *ClientBranding
+	SessionUser         *User
+	Mention             bool
+	Errs                []string
 }
 
 func (h *handler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	query := r.URL.Query()
 
-	var errs []string
-	clientID := query.Get("client_id")
-	redirectURI := query.Get("redirect_uri")
-	clubID := query.Get("club_id")
-	channelID := query.Get("channel_id")
-	state := query.Get("state")
-	if clientID == "" {
-		errs = append(errs, "Missing client_id")
-	}
-	if redirectURI == "" {
-		errs = append(errs, "Missing redirect_uri")
-	}
-	if clubID == "" {
-		errs = append(errs, "Missing club_id")
+	uiLocales := query.Get("ui_locales")
+	vars := LoginVars{
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		ClubID:              query.Get("club_id"),
+		ChannelID:           query.Get("channel_id"),
+		State:               query.Get("state"),
+		Scope:               query.Get("scope"),
+		Nonce:               query.Get("nonce"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		SAMLRequestID:       query.Get("saml_request_id"),
+		UILocales:           uiLocales,
+		Lang:                language(r, uiLocales),
 	}
-	if channelID == "" {
-		errs = append(errs, "Missing channel_id")
-	}
-	if state == "" {
-		errs = append(errs, "Missing state")
+
+	login, client, err := h.parseLoginRequest(ctx, r, query)
+	if err != nil {
+		var requestErr loginRequestError
+		if !errors.As(err, &requestErr) {
+			slog.ErrorContext(ctx, "Failed to parse login request", slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		vars.Errs = []string{requestErr.Translate(vars.Lang)}
+	} else {
+		vars.ClubID = login.ClubID
+		vars.ChannelID = login.ChannelID
+		vars.CodeChallengeMethod = login.CodeChallengeMethod
+		vars.Client = newClientBranding(*client)
+		vars.Mention = client.VerificationMode == database.VerificationModeMention
 	}
 
-	if clientID != "" {
-		client, err := h.DB.GetClient(ctx, clientID)
+	if len(vars.Errs) == 0 && query.Get("prompt") != "login" {
+		session, err := h.getSession(r)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				errs = append(errs, "Invalid client_id")
-			} else {
-				slog.ErrorContext(ctx, "Failed to get client", slog.String("client_id", clientID), slog.String("err", err.Error()))
+			slog.ErrorContext(ctx, "Failed to get session", slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if session != nil {
+			if vars.SessionUser, err = newSessionUser(*session); err != nil {
+				slog.ErrorContext(ctx, "Failed to get session user", slog.String("err", err.Error()))
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 		}
-		if client != nil && !slices.Contains(client.RedirectURIs.V, redirectURI) {
-			errs = append(errs, "Invalid redirect_uri")
-		}
 	}
 
-	if err := h.Templates().ExecuteTemplate(w, "login.gohtml", LoginVars{
-		ClientID:    clientID,
-		RedirectURI: redirectURI,
-		ClubID:      clubID,
-		ChannelID:   channelID,
-		State:       state,
-		Errs:        errs,
-	}); err != nil {
+	if err = h.Templates().ExecuteTemplate(w, "login.gohtml", vars); err != nil {
 		slog.ErrorContext(ctx, "Failed to render login template", slog.String("err", err.Error()))
 	}
 }
 
 type LoginCodeVars struct {
 	Code         string
+	CodePrefix   string
 	CheckCode    string
 	CampfireLink string
+	UILocales    string
+	Lang         string
+	Client       *ClientBranding
 }
 
 func (h *handler) LoginCode(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	query := r.URL.Query()
 
-	clientID := query.Get("client_id")
-	redirectURI := query.Get("redirect_uri")
-	clubID := query.Get("club_id")
-	channelID := query.Get("channel_id")
-	state := query.Get("state")
-	if clientID == "" {
-		http.Error(w, "Missing client_id", http.StatusBadRequest)
+	login, client, err := h.parseLoginRequest(ctx, r, r.URL.Query())
+	if err != nil {
+		var requestErr loginRequestError
+		if errors.As(err, &requestErr) {
+			http.Error(w, requestErr.Translate(language(r, r.FormValue("ui_locales"))), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to parse login request", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if redirectURI == "" {
-		http.Error(w, "Missing redirect_uri", http.StatusBadRequest)
+
+	login.Mention = client.VerificationMode == database.VerificationModeMention
+	insertedLogin, err := h.insertLogin(ctx, *login, h.Cfg.Login.Lifetimes().ForClient(*client).Code)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to insert login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if clubID == "" {
-		http.Error(w, "Missing club_id", http.StatusBadRequest)
+
+	if insertedLogin.Mention {
+		slog.InfoContext(ctx, "Generated mention login", slog.String("client_id", client.ID), slog.Int("login_id", insertedLogin.ID))
+		h.renderLoginMention(w, r, LoginMentionVars{
+			CheckCode:    insertedLogin.CheckCode,
+			CampfireLink: getChannelLink(insertedLogin.ClubID, insertedLogin.ChannelID),
+			UILocales:    insertedLogin.UILocales,
+			Lang:         language(r, insertedLogin.UILocales),
+			Client:       newClientBranding(*client),
+		})
 		return
 	}
-	if channelID == "" {
-		http.Error(w, "Missing channel_id", http.StatusBadRequest)
-		return
+	slog.InfoContext(ctx, "Generated login code", slog.String("client_id", client.ID), slog.String("code", insertedLogin.Code))
+
+	if err = h.Templates().ExecuteTemplate(w, "login_code.gohtml", LoginCodeVars{
+		Code:         insertedLogin.Code,
+		CodePrefix:   h.Cfg.Login.CodePrefix,
+		CheckCode:    insertedLogin.CheckCode,
+		CampfireLink: getChannelLink(insertedLogin.ClubID, insertedLogin.ChannelID),
+		UILocales:    insertedLogin.UILocales,
+		Lang:         language(r, insertedLogin.UILocales),
+		Client:       newClientBranding(*client),
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to render login code template", slog.String("err", err.Error()))
 	}
-	if state == "" {
-		http.Error(w, "Missing state", http.StatusBadRequest)
-		return
+}
+
+// loginRequestError is returned by parseLoginRequest for invalid parameters.
+type loginRequestError struct {
+	key  string
+	args []any
+}
+
+func newLoginRequestError(key string, args ...any) loginRequestError {
+	return loginRequestError{
+		key:  key,
+		args: args,
+	}
+}
+
+func (e loginRequestError) Error() string {
+	return e.Translate(i18n.Languages[0].String())
+}
+
+// Translate returns the message of the error in the given language.
+func (e loginRequestError) Translate(lang string) string {
+	return i18n.Translate(lang, e.key, e.args...)
+}
+
+// scopeRequestError returns the login request error for an error of resolveScope.
+func scopeRequestError(err error) loginRequestError {
+	var scopeErr scopeError
+	if !errors.As(err, &scopeErr) {
+		return newLoginRequestError("login_error.invalid_scope")
+	}
+	if scopeErr.NotAllowed {
+		return newLoginRequestError("login_error.scope_not_allowed", scopeErr.Scope)
+	}
+	return newLoginRequestError("login_error.unsupported_scope", scopeErr.Scope)
+}
+
+// channelRequestError converts a channel resolution error into a login request error.
+func channelRequestError(err error) loginRequestError {
+	switch {
+	case errors.Is(err, errChannelURLWithIDs):
+		return newLoginRequestError("login_error.channel_url_with_ids")
+	case errors.Is(err, errNoDefaultChannel):
+		return newLoginRequestError("login_error.no_default_channel")
+	case errors.Is(err, errMissingClubID):
+		return newLoginRequestError("login_error.missing_club_id")
+	case errors.Is(err, errMissingChannelID):
+		return newLoginRequestError("login_error.missing_channel_id")
+	case errors.Is(err, errChannelNotAllowed):
+		return newLoginRequestError("login_error.channel_not_allowed")
+	default:
+		// share links which can't be parsed
+		return newLoginRequestError("login_error.invalid_channel_url")
+	}
+}
+
+// parseLoginRequest validates the parameters of a login started on the login page.
+func (h *handler) parseLoginRequest(ctx context.Context, r *http.Request, values url.Values) (*database.Login, *database.Client, error) {
+	clientID := values.Get("client_id")
+	redirectURI := values.Get("redirect_uri")
+	state := values.Get("state")
+	codeChallenge := values.Get("code_challenge")
+	codeChallengeMethod := values.Get("code_challenge_method")
+	samlRequestID := values.Get("saml_request_id")
+	if clientID == "" {
+		return nil, nil, newLoginRequestError("login_error.missing_client_id")
+	}
+	if redirectURI == "" {
+		return nil, nil, newLoginRequestError("login_error.missing_redirect_uri")
+	}
+	// the RelayState of SAML requests is optional
+	if state == "" && samlRequestID == "" {
+		return nil, nil, newLoginRequestError("login_error.missing_state")
+	}
+	if responseType := values.Get("response_type"); responseType != "" && responseType != "code" {
+		return nil, nil, newLoginRequestError("login_error.unsupported_response_type")
+	}
+	if codeChallenge != "" {
+		codeChallengeMethod = cmp.Or(codeChallengeMethod, codeChallengeMethodPlain)
+		if !isValidCodeChallengeMethod(codeChallengeMethod) {
+			return nil, nil, newLoginRequestError("login_error.unsupported_code_challenge_method")
+		}
+		if !codeVerifierRegex.MatchString(codeChallenge) {
+			return nil, nil, newLoginRequestError("login_error.invalid_code_challenge")
+		}
 	}
 
 	client, err := h.DB.GetClient(ctx, clientID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Invalid client_id", http.StatusBadRequest)
-			return
+			return nil, nil, newLoginRequestError("login_error.invalid_client_id")
 		}
-		slog.ErrorContext(ctx, "Failed to get client", slog.String("client_id", clientID), slog.String("err", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("failed to get client: %w", err)
 	}
-	if !slices.Contains(client.RedirectURIs.V, redirectURI) {
-		http.Error(w, "Invalid redirect_uri", http.StatusBadRequest)
-		return
+	validRedirectURI, err := h.checkLoginRedirectURI(r, *client, redirectURI, samlRequestID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check redirect uri: %w", err)
 	}
-
-	code := xrand.RandCode()
-	checkCode := xrand.RandCode()
-	exchangeCode := xrand.RandCharCode()
-	slog.InfoContext(ctx, "Generated login code",
-		slog.String("client_id", clientID),
-		slog.String("code", code),
-		slog.String("check_code", checkCode),
-		slog.String("exchange_code", exchangeCode),
-	)
-
-	if err = h.DB.InsertLogin(ctx, database.Login{
-		ClientID:     clientID,
-		Code:         code,
-		CheckCode:    checkCode,
-		ExchangeCode: exchangeCode,
-		RedirectURI:  redirectURI,
-		ClubID:       clubID,
-		ChannelID:    channelID,
-		State:        state,
-	}); err != nil {
-		slog.ErrorContext(ctx, "Failed to insert login", slog.String("client_id", clientID), slog.String("code", code), slog.String("err", err.Error()))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	if !validRedirectURI {
+		return nil, nil, newLoginRequestError("login_error.invalid_redirect_uri")
+	}
+	if client.Public && codeChallenge == "" && samlRequestID == "" {
+		return nil, nil, newLoginRequestError("login_error.missing_code_challenge")
+	}
+	scope, err := resolveScope(*client, values.Get("scope"))
+	if err != nil {
+		return nil, nil, scopeRequestError(err)
+	}
+	channels, err := h.DB.GetClientChannels(ctx, client.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client channels: %w", err)
+	}
+	clubID, channelID, err := resolveChannelURL(values.Get("channel_url"), values.Get("club_id"), values.Get("channel_id"))
+	if err != nil {
+		return nil, nil, channelRequestError(err)
+	}
+	clubID, channelID, err = resolveChannel(channels, clubID, channelID)
+	if err != nil {
+		return nil, nil, channelRequestError(err)
+	}
+	if err = h.CheckChannelReadable(ctx, channelID); err != nil {
+		slog.WarnContext(ctx, "Login channel can't be read", slog.String("client_id", client.ID), slog.String("channel_id", channelID), slog.String("err", err.Error()))
+		return nil, nil, newLoginRequestError("login_error.channel_unreadable")
 	}
 
-	if err = h.Templates().ExecuteTemplate(w, "login_code.gohtml", LoginCodeVars{
-		Code:         code,
-		CheckCode:    checkCode,
-		CampfireLink: getChannelLink(clubID, channelID),
-	}); err != nil {
-		slog.ErrorContext(ctx, "Failed to render login code template", slog.String("err", err.Error()))
+	login := database.Login{
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		ClubID:              clubID,
+		ChannelID:           channelID,
+		State:               state,
+		Scope:               scope,
+		Nonce:               values.Get("nonce"),
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		UILocales:           values.Get("ui_locales"),
+	}
+	if samlRequestID != "" {
+		login.SAMLRequestID = &samlRequestID
 	}
+	return &login, client, nil
 }
 
 func (h *handler) LoginQRCode(w http.ResponseWriter, r *http.Request) {
@@ -180,7 +312,14 @@ func (h *handler) LoginQRCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	qrW := standard.NewWithWriter(&responseWriteCloser{w}, standard.WithLogoImage(h.Logo),
+	logo := h.Logo
+	if login, err := h.DB.GetLoginByCode(ctx, code); err == nil {
+		logo = h.loginLogo(ctx, *login)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		slog.ErrorContext(ctx, "Failed to get login", slog.String("err", err.Error()))
+	}
+
+	qrW := standard.NewWithWriter(&responseWriteCloser{w}, standard.WithLogoImage(logo),
 		standard.WithBgTransparent(),
 		standard.WithBuiltinImageEncoder(standard.PNG_FORMAT),
 		standard.WithLogoSafeZone(),
@@ -216,8 +355,15 @@ func (h *handler) LoginRe(w http.ResponseWriter, r *http.Request) {
 }
 
 type LoginCheckVars struct {
-	User        User
-	RedirectURI string
+	User      User
+	CheckCode string
+	Scopes    []Scope
+	Lang      string
+	Client    *ClientBranding
+}
+
+type LoginExpiredVars struct {
+	Lang string
 }
 
 type User struct {
@@ -239,9 +385,7 @@ func (h *handler) LoginCheck(w http.ResponseWriter, r *http.Request) {
 	login, err := h.DB.GetLoginByCheckCode(ctx, checkCode)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			if err = h.Templates().ExecuteTemplate(w, "login_expired.gohtml", nil); err != nil {
-				slog.ErrorContext(ctx, "Failed to render login expired template", slog.String("err", err.Error()))
-			}
+			h.renderLoginExpired(w, r, r.FormValue("ui_locales"))
 			return
 		}
 		slog.ErrorContext(ctx, "Failed to get login", slog.String("check_code", checkCode), slog.String("err", err.Error()))
@@ -249,23 +393,47 @@ func (h *handler) LoginCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.renderLoginState(w, r, *login)
+}
+
+// renderLoginState renders the current state of the login.
+func (h *handler) renderLoginState(w http.ResponseWriter, r *http.Request, login database.Login) {
+	ctx := r.Context()
+
+	branding, err := h.getClientBranding(ctx, login.ClientID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client branding", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if login.User == nil && login.Mention {
+		h.renderLoginMention(w, r, LoginMentionVars{
+			CheckCode:    login.CheckCode,
+			Mentioned:    login.MentionUserID != nil,
+			CampfireLink: getChannelLink(login.ClubID, login.ChannelID),
+			UILocales:    login.UILocales,
+			Lang:         language(r, login.UILocales),
+			Client:       branding,
+		})
+		return
+	}
+
 	if login.User == nil {
 		if err = h.Templates().ExecuteTemplate(w, "login_code.gohtml", LoginCodeVars{
 			Code:         login.Code,
+			CodePrefix:   h.Cfg.Login.CodePrefix,
 			CheckCode:    login.CheckCode,
 			CampfireLink: getChannelLink(login.ClubID, login.ChannelID),
+			UILocales:    login.UILocales,
+			Lang:         language(r, login.UILocales),
+			Client:       branding,
 		}); err != nil {
 			slog.ErrorContext(ctx, "Failed to render login code template", slog.String("err", err.Error()))
 		}
 		return
 	}
 
-	u, _ := url.Parse(login.RedirectURI)
-	q := u.Query()
-	q.Set("code", login.ExchangeCode)
-	q.Set("state", login.State)
-	u.RawQuery = q.Encode()
-
 	var user struct {
 		ID          string `json:"id"`
 		DisplayName string `json:"displayName"`
@@ -285,12 +453,199 @@ func (h *handler) LoginCheck(w http.ResponseWriter, r *http.Request) {
 			Username:    user.Username,
 			AvatarURL:   cmp.Or(user.AvatarURL, "/static/default_avatar.png"),
 		},
-		RedirectURI: u.String(),
+		CheckCode: login.CheckCode,
+		Scopes:    requestedScopes(login.Scope, loginRequiredScopes(login)...),
+		Lang:      language(r, login.UILocales),
+		Client:    branding,
 	}); err != nil {
 		slog.ErrorContext(ctx, "Failed to render login code template", slog.String("err", err.Error()))
 	}
 }
 
+// LoginConsent stores the scopes the user granted.
+func (h *handler) LoginConsent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	checkCode := r.PostForm.Get("check_code")
+	if checkCode == "" {
+		http.Error(w, "Missing check_code", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.DB.GetLoginByCheckCode(ctx, checkCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.renderLoginExpired(w, r, r.FormValue("ui_locales"))
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get login", slog.String("check_code", checkCode), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if login.User == nil || login.Device || login.API {
+		http.Error(w, "Login is not verified", http.StatusBadRequest)
+		return
+	}
+
+	if h.Cfg.Session.Enabled {
+		if err = h.createSession(w, r, *login); err != nil {
+			slog.ErrorContext(ctx, "Failed to create session", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		}
+	}
+
+	h.completeLogin(w, r, *login, grantScope(login.Scope, r.PostForm["scope"], loginRequiredScopes(*login)...))
+}
+
+// LoginContinue verifies a new login with the user of the session.
+func (h *handler) LoginContinue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.getSession(r)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get session", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Error(w, i18n.Translate(language(r, r.PostForm.Get("ui_locales")), "login_error.session_expired"), http.StatusUnauthorized)
+		return
+	}
+
+	login, client, err := h.parseLoginRequest(ctx, r, r.PostForm)
+	if err != nil {
+		var requestErr loginRequestError
+		if errors.As(err, &requestErr) {
+			http.Error(w, requestErr.Translate(language(r, r.FormValue("ui_locales"))), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to parse login request", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	loginUser, err := h.sessionLoginUser(ctx, *session, *client, login.ClubID)
+	if err != nil {
+		if errors.Is(err, server.ErrClubMemberNotAllowed) {
+			http.Error(w, i18n.Translate(language(r, login.UILocales), "login_error.not_allowed"), http.StatusForbidden)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get session login user", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	lifetimes := h.Cfg.Login.Lifetimes()
+	insertedLogin, err := h.insertLogin(ctx, *login, lifetimes.ForClient(*client).Code)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to insert login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err = h.DB.UpdateLoginUser(ctx, insertedLogin.ID, *loginUser, lifetimes); err != nil {
+		slog.ErrorContext(ctx, "Failed to update login user", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	insertedLogin.User = &loginUser.User
+	insertedLogin.ClubMember = loginUser.ClubMember
+
+	// the user has to consent again if the scope was never granted or the grant was revoked
+	granted, err := h.isGranted(ctx, client.ID, session.UserID, insertedLogin.Scope)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check grant", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !granted {
+		http.Redirect(w, r, "/login/check?check_code="+url.QueryEscape(insertedLogin.CheckCode), http.StatusSeeOther)
+		return
+	}
+
+	h.completeLogin(w, r, *insertedLogin, insertedLogin.Scope)
+}
+
+// isGranted reports whether the user already granted the client all the given scopes.
+func (h *handler) isGranted(ctx context.Context, clientID string, userID string, scope string) (bool, error) {
+	grant, err := h.DB.GetGrant(ctx, clientID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, s := range strings.Fields(scope) {
+		if !hasScope(grant.Scope, s) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// completeLogin finishes a verified login with the granted scope.
+func (h *handler) completeLogin(w http.ResponseWriter, r *http.Request, login database.Login, grantedScope string) {
+	ctx := r.Context()
+
+	if login.SAMLRequestID != nil {
+		h.writeSAMLResponse(w, r, login, grantedScope)
+		return
+	}
+
+	if err := h.DB.UpdateLoginGrantedScope(ctx, login.ID, grantedScope, h.Cfg.Login.Lifetimes()); err != nil {
+		slog.ErrorContext(ctx, "Failed to update login granted scope", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	u, _ := url.Parse(login.RedirectURI)
+	q := u.Query()
+	q.Set("code", login.ExchangeCode)
+	q.Set("state", login.State)
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+// language returns the language the login pages are rendered in.
+func language(r *http.Request, uiLocales string) string {
+	return i18n.Match(uiLocales, r.Header.Get("Accept-Language"))
+}
+
+// loginCodeAttempts is how often colliding codes are generated again.
+const loginCodeAttempts = 5
+
+// insertLogin generates the codes of the login and inserts it.
+func (h *handler) insertLogin(ctx context.Context, login database.Login, codeLifetime time.Duration) (*database.Login, error) {
+	for range loginCodeAttempts {
+		login.Code = h.Cfg.Login.NewCode()
+		login.CheckCode = xrand.RandCharCode()
+		login.ExchangeCode = xrand.RandCharCode()
+
+		id, err := h.DB.InsertLogin(ctx, login, codeLifetime)
+		if errors.Is(err, database.ErrLoginCodeUsed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		login.ID = id
+		return &login, nil
+	}
+
+	return nil, fmt.Errorf("failed to generate unique login codes after %d attempts", loginCodeAttempts)
+}
+
 func getChannelLink(clubID string, channelID string) string {
 	v := url.Values{}
 	v.Set("r", "clubs")
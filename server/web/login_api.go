@@ -0,0 +1,221 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const loginAPIErrNotFound = "not_found"
+
+type loginCreateRequest struct {
+	ClubID     string `json:"club_id"`
+	ChannelID  string `json:"channel_id"`
+	ChannelURL string `json:"channel_url"`
+	Scope      string `json:"scope"`
+}
+
+type loginResponse struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Code   string `json:"code"`
+	// Message is what the user has to post in the channel, the code with the configured prefix.
+	Message     string    `json:"message"`
+	ClubID      string    `json:"club_id"`
+	ChannelID   string    `json:"channel_id"`
+	ChannelLink string    `json:"channel_link"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	ExpiresIn   int       `json:"expires_in"`
+	// Token is only set once the login got verified, the login is consumed by returning it.
+	Token *tokenResponse `json:"token,omitempty"`
+}
+
+// loginMessage returns the message the user has to post in the channel to verify a login.
+func loginMessage(code string, codePrefix string) string {
+	if codePrefix == "" {
+		return code
+	}
+	return codePrefix + " " + code
+}
+
+func newLoginResponse(login database.Login, codePrefix string) loginResponse {
+	return loginResponse{
+		ID:          login.ID,
+		Status:      loginState(&login),
+		Code:        login.Code,
+		Message:     loginMessage(login.Code, codePrefix),
+		ClubID:      login.ClubID,
+		ChannelID:   login.ChannelID,
+		ChannelLink: getChannelLink(login.ClubID, login.ChannelID),
+		ExpiresAt:   login.ExpiresAt,
+		ExpiresIn:   max(int(time.Until(login.ExpiresAt).Seconds()), 0),
+	}
+}
+
+// CreateLogin starts a login for clients rendering their own UI.
+func (h *handler) CreateLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var rq loginCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&rq); err != nil && !errors.Is(err, io.EOF) {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid json body: "+err.Error())
+		return
+	}
+
+	scope, err := resolveScope(*client, rq.Scope)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidScope, err.Error())
+		return
+	}
+	channels, err := h.DB.GetClientChannels(ctx, client.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client channels", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+	clubID, channelID, err := resolveChannelURL(rq.ChannelURL, rq.ClubID, rq.ChannelID)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid channel_url: "+err.Error())
+		return
+	}
+	clubID, channelID, err = resolveChannel(channels, clubID, channelID)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid channel: "+err.Error())
+		return
+	}
+	if err = h.CheckChannelReadable(ctx, channelID); err != nil {
+		slog.WarnContext(ctx, "Login channel can't be read", slog.String("client_id", client.ID), slog.String("channel_id", channelID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Channel can't be read by campfire-auth, codes posted in it could not be verified")
+		return
+	}
+
+	// headless logins have no consent screen
+	login, err := h.insertLogin(ctx, database.Login{
+		ClientID:  client.ID,
+		ClubID:    clubID,
+		ChannelID: channelID,
+		Scope:     scope,
+		API:       true,
+	}, h.Cfg.Login.Lifetimes().ForClient(*client).Code)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to insert login", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	createdLogin, err := h.DB.GetLoginByClientIDID(ctx, client.ID, login.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get login", slog.String("client_id", client.ID), slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/api/logins/%d", h.Cfg.Server.PublicURL, login.ID))
+	h.writeOAuthJSON(w, r, http.StatusCreated, newLoginResponse(*createdLogin, h.Cfg.Login.CodePrefix))
+}
+
+// GetLogin returns the status of a headless login.
+func (h *handler) GetLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	login, ok := h.getAPILogin(w, r, client)
+	if !ok {
+		return
+	}
+
+	rs := newLoginResponse(*login, h.Cfg.Login.CodePrefix)
+	if rs.Status == loginStateVerified {
+		login, err := h.DB.DeleteLoginByClientIDExchangeCode(ctx, client.ID, login.ExchangeCode)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.writeOAuthError(w, r, http.StatusNotFound, loginAPIErrNotFound, "Login not found")
+				return
+			}
+			slog.ErrorContext(ctx, "Failed to delete login by exchange code", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+
+		if rs.Token, err = h.newLoginTokenResponse(r, client, *login); err != nil {
+			if errors.Is(err, errLoginNotGranted) {
+				h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Login was not granted")
+				return
+			}
+			slog.ErrorContext(ctx, "Failed to create login token response", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return
+		}
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusOK, rs)
+}
+
+// DeleteLogin cancels a headless login.
+func (h *handler) DeleteLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusNotFound, loginAPIErrNotFound, "Login not found")
+		return
+	}
+
+	rows, err := h.DB.DeleteLoginByClientIDID(ctx, client.ID, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete login", slog.String("client_id", client.ID), slog.Int("login_id", id), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+	if rows == 0 {
+		h.writeOAuthError(w, r, http.StatusNotFound, loginAPIErrNotFound, "Login not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) getAPILogin(w http.ResponseWriter, r *http.Request, client *database.Client) (*database.Login, bool) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusNotFound, loginAPIErrNotFound, "Login not found")
+		return nil, false
+	}
+
+	login, err := h.DB.GetLoginByClientIDID(ctx, client.ID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusNotFound, loginAPIErrNotFound, "Login not found")
+			return nil, false
+		}
+		slog.ErrorContext(ctx, "Failed to get login", slog.String("client_id", client.ID), slog.Int("login_id", id), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return nil, false
+	}
+
+	return login, true
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	loginStatePending  = "pending"
+	loginStateVerified = "verified"
+	loginStateExpired  = "expired"
+
+	loginEventsKeepAlive = 15 * time.Second
+)
+
+// LoginEvents streams the state of a login as server-sent events until it got verified or expired.
+func (h *handler) LoginEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	checkCode := r.URL.Query().Get("check_code")
+	if checkCode == "" {
+		http.Error(w, "Missing check_code", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.getLoginEventsLogin(r, checkCode)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	rc := http.NewResponseController(w)
+
+	if login == nil {
+		writeLoginEvent(w, rc, loginStateExpired)
+		return
+	}
+
+	updates, unsubscribe := h.SubscribeLoginUpdates(login.ID)
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(loginEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	// the login might have been verified before subscribing, so it is fetched on every wake up
+	var lastState string
+	for {
+		if login, err = h.getLoginEventsLogin(r, checkCode); err != nil {
+			return
+		}
+
+		state := loginState(login)
+		if state != lastState {
+			if err = writeLoginEvent(w, rc, state); err != nil {
+				return
+			}
+			lastState = state
+		}
+		if state != loginStatePending {
+			return
+		}
+
+		expires := time.NewTimer(time.Until(login.ExpiresAt))
+		select {
+		case <-ctx.Done():
+		case <-updates:
+		case <-expires.C:
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err == nil {
+				err = rc.Flush()
+			}
+		}
+		expires.Stop()
+		if ctx.Err() != nil || err != nil {
+			return
+		}
+	}
+}
+
+// getLoginEventsLogin returns the login of the check code, nil if it expired.
+func (h *handler) getLoginEventsLogin(r *http.Request, checkCode string) (*database.Login, error) {
+	login, err := h.DB.GetLoginByCheckCode(r.Context(), checkCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		slog.ErrorContext(r.Context(), "Failed to get login", slog.String("check_code", checkCode), slog.String("err", err.Error()))
+		return nil, err
+	}
+	return login, nil
+}
+
+func loginState(login *database.Login) string {
+	if login == nil || time.Now().After(login.ExpiresAt) {
+		return loginStateExpired
+	}
+	if login.User != nil {
+		return loginStateVerified
+	}
+	return loginStatePending
+}
+
+func writeLoginEvent(w http.ResponseWriter, rc *http.ResponseController, state string) error {
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", state, state); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,317 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/topi314/campfire-auth/server"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+	"github.com/topi314/campfire-auth/server/i18n"
+)
+
+// maxLoginMentionAttempts is how many wrong codes can be entered for a mention login.
+const maxLoginMentionAttempts = 5
+
+type LoginMentionVars struct {
+	CheckCode    string
+	Mentioned    bool
+	Username     string
+	CampfireLink string
+	UILocales    string
+	Lang         string
+	Client       *ClientBranding
+	Errs         []string
+}
+
+func (h *handler) renderLoginMention(w http.ResponseWriter, r *http.Request, vars LoginMentionVars) {
+	if err := h.Templates().ExecuteTemplate(w, "login_mention.gohtml", vars); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to render login mention template", slog.String("err", err.Error()))
+	}
+}
+
+// LoginMention mentions the entered Campfire user with the code of the login.
+func (h *handler) LoginMention(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	login, ok := h.getMentionLogin(w, r)
+	if !ok {
+		return
+	}
+	if login.MentionUserID != nil {
+		h.renderLoginState(w, r, *login)
+		return
+	}
+
+	client, err := h.DB.GetClient(ctx, login.ClientID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get login client", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	lang := language(r, login.UILocales)
+	username := strings.TrimPrefix(strings.TrimSpace(r.PostForm.Get("username")), "@")
+	vars := LoginMentionVars{
+		CheckCode:    login.CheckCode,
+		Username:     username,
+		CampfireLink: getChannelLink(login.ClubID, login.ChannelID),
+		UILocales:    login.UILocales,
+		Lang:         lang,
+		Client:       newClientBranding(*client),
+	}
+	if username == "" {
+		vars.Errs = []string{i18n.Translate(lang, "login_mention.user_not_found")}
+		h.renderLoginMention(w, r, vars)
+		return
+	}
+
+	users, err := h.Campfire.SearchUsers(ctx, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to search users", slog.String("username", username), slog.String("err", err.Error()))
+		vars.Errs = []string{i18n.Translate(lang, "login_mention.send_failed")}
+		h.renderLoginMention(w, r, vars)
+		return
+	}
+	var user *campfire.User
+	for _, u := range users {
+		if strings.EqualFold(u.Username, username) {
+			user = &u
+			break
+		}
+	}
+	if user == nil {
+		vars.Errs = []string{i18n.Translate(lang, "login_mention.user_not_found")}
+		h.renderLoginMention(w, r, vars)
+		return
+	}
+
+	// users who can't verify the login are not mentioned
+	if _, err = h.GetLoginClubMember(ctx, *client, login.ClubID, user.ID); err != nil {
+		if errors.Is(err, server.ErrClubMemberNotAllowed) {
+			vars.Errs = []string{i18n.Translate(lang, "login_mention.not_allowed")}
+		} else {
+			slog.ErrorContext(ctx, "Failed to get login club member", slog.String("club_id", login.ClubID), slog.String("user_id", user.ID), slog.String("err", err.Error()))
+			vars.Errs = []string{i18n.Translate(lang, "login_mention.send_failed")}
+		}
+		h.renderLoginMention(w, r, vars)
+		return
+	}
+
+	// claim the login first, so concurrent requests can't mention several users
+	if err = h.DB.UpdateLoginMention(ctx, login.ID, user.ID); err != nil {
+		if errors.Is(err, database.ErrLoginMentioned) {
+			vars.Errs = []string{i18n.Translate(lang, "login_mention.already_mentioned")}
+			h.renderLoginMention(w, r, vars)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to update login mention", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	content := i18n.Translate(lang, "login_mention.message", user.Username, newClientBranding(*client).Name, login.Code)
+	message, err := h.Campfire.SendMessage(ctx, login.ChannelID, content, []string{user.ID})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send login mention", slog.String("channel_id", login.ChannelID), slog.String("err", err.Error()))
+		if err = h.DB.ReleaseLoginMention(ctx, login.ID, user.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to release login mention", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		}
+		vars.Errs = []string{i18n.Translate(lang, "login_mention.send_failed")}
+		h.renderLoginMention(w, r, vars)
+		return
+	}
+
+	if err = h.DB.UpdateLoginMentionMessage(ctx, login.ID, message.Id); err != nil {
+		slog.ErrorContext(ctx, "Failed to update login mention message", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "Mentioned login user", slog.String("client_id", client.ID), slog.Int("login_id", login.ID), slog.String("user_id", user.ID))
+
+	vars.Mentioned = true
+	h.renderLoginMention(w, r, vars)
+}
+
+// LoginMentionConfirm verifies a mention login once the user entered the code they were mentioned with.
+func (h *handler) LoginMentionConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	login, ok := h.getMentionLogin(w, r)
+	if !ok {
+		return
+	}
+	if login.MentionUserID == nil || login.MessageID == nil {
+		h.renderLoginState(w, r, *login)
+		return
+	}
+
+	if !matchLoginMentionCode(login.Code, r.PostForm.Get("code")) {
+		attempts, err := h.DB.IncrementLoginMentionAttempts(ctx, login.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to increment login mention attempts", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if attempts >= maxLoginMentionAttempts {
+			if _, err = h.DB.DeleteLoginByClientIDID(ctx, login.ClientID, login.ID); err != nil {
+				slog.ErrorContext(ctx, "Failed to delete login", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			h.renderLoginExpired(w, r, login.UILocales)
+			return
+		}
+
+		branding, err := h.getClientBranding(ctx, login.ClientID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get client branding", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		lang := language(r, login.UILocales)
+		h.renderLoginMention(w, r, LoginMentionVars{
+			CheckCode:    login.CheckCode,
+			Mentioned:    true,
+			CampfireLink: getChannelLink(login.ClubID, login.ChannelID),
+			UILocales:    login.UILocales,
+			Lang:         lang,
+			Client:       branding,
+			Errs:         []string{i18n.Translate(lang, "login_mention.invalid_code")},
+		})
+		return
+	}
+
+	client, err := h.DB.GetClient(ctx, login.ClientID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get login client", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	loginUser, err := h.mentionLoginUser(ctx, *login, *client)
+	if errors.Is(err, server.ErrClubMemberNotAllowed) {
+		lang := language(r, login.UILocales)
+		h.renderLoginMention(w, r, LoginMentionVars{
+			CheckCode:    login.CheckCode,
+			Mentioned:    true,
+			CampfireLink: getChannelLink(login.ClubID, login.ChannelID),
+			UILocales:    login.UILocales,
+			Lang:         lang,
+			Client:       newClientBranding(*client),
+			Errs:         []string{i18n.Translate(lang, "login_mention.not_allowed")},
+		})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get mention login user", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err = h.DB.UpdateLoginUser(ctx, login.ID, *loginUser, h.Cfg.Login.Lifetimes()); err != nil {
+		slog.ErrorContext(ctx, "Failed to update login user", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "Verified mention login", slog.String("client_id", login.ClientID), slog.Int("login_id", login.ID))
+
+	verifiedLogin, err := h.DB.GetLoginByCheckCode(ctx, login.CheckCode)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get login", slog.Int("login_id", login.ID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.renderLoginState(w, r, *verifiedLogin)
+}
+
+// getMentionLogin returns the pending mention login of the posted check code.
+func (h *handler) getMentionLogin(w http.ResponseWriter, r *http.Request) (*database.Login, bool) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	checkCode := r.PostForm.Get("check_code")
+	if checkCode == "" {
+		http.Error(w, "Missing check_code", http.StatusBadRequest)
+		return nil, false
+	}
+
+	login, err := h.DB.GetLoginByCheckCode(ctx, checkCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.renderLoginExpired(w, r, r.PostForm.Get("ui_locales"))
+			return nil, false
+		}
+		slog.ErrorContext(ctx, "Failed to get login", slog.String("check_code", checkCode), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !login.Mention {
+		http.Error(w, "Login is not verified by mention", http.StatusBadRequest)
+		return nil, false
+	}
+	if login.User != nil {
+		h.renderLoginState(w, r, *login)
+		return nil, false
+	}
+
+	return login, true
+}
+
+// mentionLoginUser returns the verified user of a mention login.
+func (h *handler) mentionLoginUser(ctx context.Context, login database.Login, client database.Client) (*database.LoginUser, error) {
+	user, err := h.Campfire.GetUserByID(ctx, *login.MentionUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	userData, err := json.Marshal(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user: %w", err)
+	}
+
+	loginUser := database.LoginUser{
+		User:      userData,
+		MessageID: *login.MessageID,
+	}
+
+	member, err := h.GetLoginClubMember(ctx, client, login.ClubID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		memberData, err := json.Marshal(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal club member: %w", err)
+		}
+		rawMemberData := json.RawMessage(memberData)
+		loginUser.ClubMember = &rawMemberData
+	}
+
+	return &loginUser, nil
+}
+
+// matchLoginMentionCode compares the entered code with the code of the login.
+func matchLoginMentionCode(code string, entered string) bool {
+	normalize := strings.NewReplacer(" ", "", "-", "")
+	code = strings.ToLower(normalize.Replace(code))
+	entered = strings.ToLower(normalize.Replace(strings.TrimSpace(entered)))
+	return subtle.ConstantTimeCompare([]byte(code), []byte(entered)) == 1
+}
+
+func (h *handler) renderLoginExpired(w http.ResponseWriter, r *http.Request, uiLocales string) {
+	if err := h.Templates().ExecuteTemplate(w, "login_expired.gohtml", LoginExpiredVars{
+		Lang: language(r, uiLocales),
+	}); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to render login expired template", slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,31 @@
+package web
+
+import "testing"
+
+func TestMatchLoginMentionCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		entered string
+		want    bool
+	}{
+		{name: "exact", code: "123456", entered: "123456", want: true},
+		{name: "surrounding spaces", code: "123456", entered: " 123456 ", want: true},
+		{name: "spaces between", code: "123456", entered: "123 456", want: true},
+		{name: "different case", code: "abc123", entered: "ABC123", want: true},
+		{name: "words with dashes", code: "maple-otter-prism", entered: "maple-otter-prism", want: true},
+		{name: "words with spaces", code: "maple-otter-prism", entered: "Maple Otter Prism", want: true},
+		{name: "words without separators", code: "maple-otter-prism", entered: "mapleotterprism", want: true},
+		{name: "wrong code", code: "123456", entered: "123457", want: false},
+		{name: "prefix of code", code: "123456", entered: "12345", want: false},
+		{name: "code with suffix", code: "123456", entered: "1234567", want: false},
+		{name: "empty", code: "123456", entered: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchLoginMentionCode(tt.code, tt.entered); got != tt.want {
+				t.Errorf("matchLoginMentionCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
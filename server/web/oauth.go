@@ -0,0 +1,428 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeClientCredentials = "client_credentials"
+
+	tokenTypeBearer = "Bearer"
+)
+
+// OAuth 2.0 error codes as defined in RFC 6749 section 5.2.
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidGrant         = "invalid_grant"
+	oauthErrInvalidScope         = "invalid_scope"
+	oauthErrUnauthorizedClient   = "unauthorized_client"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrServerError          = "server_error"
+)
+
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type tokenResponse struct {
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type"`
+	ExpiresIn    int         `json:"expires_in"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	Scope        string      `json:"scope,omitempty"`
+	IDToken      string      `json:"id_token,omitempty"`
+	User         *ScopedUser `json:"user,omitempty"`
+	// ClubMember is the membership of the user in the club of the login.
+	ClubMember *campfire.ClubMember `json:"club_member,omitempty"`
+	// MessageID is the Campfire message which contained the code of the login.
+	MessageID  *string    `json:"message_id,omitempty"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+func (h *handler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid form body")
+		return
+	}
+
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantTypeAuthorizationCode:
+		h.tokenAuthorizationCode(w, r, client)
+	case grantTypeRefreshToken:
+		h.tokenRefreshToken(w, r, client)
+	case grantTypeDeviceCode:
+		h.tokenDeviceCode(w, r, client)
+	case grantTypeClientCredentials:
+		h.tokenClientCredentials(w, r, client)
+	case "":
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing grant_type")
+	default:
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrUnsupportedGrantType, "Unsupported grant_type: "+grantType)
+	}
+}
+
+func (h *handler) tokenAuthorizationCode(w http.ResponseWriter, r *http.Request, client *database.Client) {
+	ctx := r.Context()
+
+	code := r.PostForm.Get("code")
+	if code == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing code")
+		return
+	}
+	redirectURI := r.PostForm.Get("redirect_uri")
+	if redirectURI == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing redirect_uri")
+		return
+	}
+
+	// the code is only used up once the request is verified
+	login, err := h.DB.GetLoginByClientIDExchangeCode(ctx, client.ID, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid code")
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get login by exchange code", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	if login.RedirectURI != redirectURI {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "redirect_uri does not match the authorization request")
+		return
+	}
+
+	if login.CodeChallenge != "" {
+		codeVerifier := r.PostForm.Get("code_verifier")
+		if codeVerifier == "" {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing code_verifier")
+			return
+		}
+		if !verifyCodeChallenge(login.CodeChallenge, login.CodeChallengeMethod, codeVerifier) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid code_verifier")
+			return
+		}
+	} else if client.Public {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Public clients must use PKCE")
+		return
+	}
+
+	if login, err = h.DB.DeleteLoginByClientIDExchangeCode(ctx, client.ID, code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid code")
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to delete login by exchange code", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeLoginTokenResponse(w, r, client, *login)
+}
+
+// writeLoginTokenResponse issues tokens for the verified user of the login.
+func (h *handler) writeLoginTokenResponse(w http.ResponseWriter, r *http.Request, client *database.Client, login database.Login) {
+	rs, err := h.newLoginTokenResponse(r, client, login)
+	if errors.Is(err, errLoginNotGranted) {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Login was not granted")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create login token response", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeTokenResponse(w, r, *rs)
+}
+
+var errLoginNotGranted = errors.New("login was not granted")
+
+// newLoginTokenResponse issues tokens for the verified user of the login.
+func (h *handler) newLoginTokenResponse(r *http.Request, client *database.Client, login database.Login) (*tokenResponse, error) {
+	if login.User == nil || login.GrantedScope == nil {
+		return nil, errLoginNotGranted
+	}
+
+	var user campfire.User
+	if err := json.Unmarshal(*login.User, &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal login user: %w", err)
+	}
+
+	clubMember, err := loginClubMember(login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal login club member: %w", err)
+	}
+
+	scope := *login.GrantedScope
+
+	var idToken string
+	if hasScope(scope, scopeOpenID) {
+		if idToken, err = h.newIDToken(r, login, user, scope); err != nil {
+			return nil, fmt.Errorf("failed to create id token: %w", err)
+		}
+	}
+
+	// the grant is recorded before the tokens are issued
+	if err = h.DB.UpsertGrant(r.Context(), client.ID, user.ID, scope); err != nil {
+		return nil, fmt.Errorf("failed to upsert grant: %w", err)
+	}
+	rs, err := h.issueTokens(r.Context(), client.ID, user.ID, scope, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue tokens: %w", err)
+	}
+	scopedUser := newScopedUser(user, scope)
+	rs.IDToken = idToken
+	rs.User = &scopedUser
+	rs.ClubMember = clubMember
+	rs.MessageID = login.MessageID
+	rs.VerifiedAt = login.VerifiedAt
+
+	return rs, nil
+}
+
+func loginClubMember(login database.Login) (*campfire.ClubMember, error) {
+	if login.ClubMember == nil {
+		return nil, nil
+	}
+
+	var member *campfire.ClubMember
+	if err := json.Unmarshal(*login.ClubMember, &member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (h *handler) tokenRefreshToken(w http.ResponseWriter, r *http.Request, client *database.Client) {
+	ctx := r.Context()
+
+	token := r.PostForm.Get("refresh_token")
+	if token == "" {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Missing refresh_token")
+		return
+	}
+
+	refreshToken, err := h.DB.DeleteRefreshTokenByClientIDToken(ctx, client.ID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid refresh_token")
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to delete refresh token", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	// the client may request a subset of the originally granted scopes
+	scope := refreshToken.Scope
+	if requestedScope := r.PostForm.Get("scope"); requestedScope != "" {
+		for _, s := range strings.Fields(requestedScope) {
+			if !hasScope(refreshToken.Scope, s) {
+				h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidScope, "Scope exceeds the originally granted scope: "+s)
+				return
+			}
+		}
+		scope = requestedScope
+	}
+
+	rs, err := h.issueTokens(ctx, client.ID, refreshToken.UserID, scope, refreshToken.Family)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to issue tokens", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeTokenResponse(w, r, *rs)
+}
+
+// tokenClientCredentials issues an access token which is not bound to a user.
+func (h *handler) tokenClientCredentials(w http.ResponseWriter, r *http.Request, client *database.Client) {
+	ctx := r.Context()
+
+	if client.Public {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrUnauthorizedClient, "Public clients can't use the client_credentials grant")
+		return
+	}
+
+	scope, err := resolveClientScope(*client, r.PostForm.Get("scope"))
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidScope, err.Error())
+		return
+	}
+
+	lifetime := time.Duration(h.Cfg.OAuth.ClientTokenLifetime)
+	accessToken, err := h.issueAccessToken(ctx, client.ID, "", scope, lifetime, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to issue access token", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeTokenResponse(w, r, tokenResponse{
+		AccessToken: accessToken.Token,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int(lifetime.Seconds()),
+		Scope:       scope,
+	})
+}
+
+// issueTokens issues a new access token and a new refresh token for the given user.
+func (h *handler) issueTokens(ctx context.Context, clientID string, userID string, scope string, family string) (*tokenResponse, error) {
+	if family == "" {
+		family = xrand.RandSecret()
+	}
+	lifetime := time.Duration(h.Cfg.OAuth.AccessTokenLifetime)
+
+	accessToken, err := h.issueAccessToken(ctx, clientID, userID, scope, lifetime, &family)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := database.RefreshToken{
+		Token:     xrand.RandSecret(),
+		ClientID:  clientID,
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(h.Cfg.OAuth.RefreshTokenLifetime)),
+		Family:    family,
+	}
+	if err = h.DB.InsertRefreshToken(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		AccessToken:  accessToken.Token,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int(lifetime.Seconds()),
+		RefreshToken: refreshToken.Token,
+		Scope:        scope,
+	}, nil
+}
+
+// issueAccessToken issues a new access token, client tokens have no user ID.
+func (h *handler) issueAccessToken(ctx context.Context, clientID string, userID string, scope string, lifetime time.Duration, family *string) (*database.AccessToken, error) {
+	accessToken := database.AccessToken{
+		Token:     xrand.RandSecret(),
+		ClientID:  clientID,
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: time.Now().UTC().Add(lifetime),
+		Family:    family,
+	}
+	if err := h.DB.InsertAccessToken(ctx, accessToken); err != nil {
+		return nil, err
+	}
+
+	return &accessToken, nil
+}
+
+// authenticateClient authenticates the client of a token request.
+func (h *handler) authenticateClient(w http.ResponseWriter, r *http.Request) (*database.Client, bool) {
+	ctx := r.Context()
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Has("client_secret") {
+			h.writeOAuthError(w, r, http.StatusBadRequest, oauthErrInvalidRequest, "Multiple client authentication methods used")
+			return nil, false
+		}
+
+		// RFC 6749 section 2.3.1 requires the credentials to be form-urlencoded before being used in basic auth.
+		var err error
+		if clientID, err = url.QueryUnescape(clientID); err != nil {
+			h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Invalid client_id encoding")
+			return nil, false
+		}
+		if clientSecret, err = url.QueryUnescape(clientSecret); err != nil {
+			h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Invalid client_secret encoding")
+			return nil, false
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	if clientID == "" {
+		h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Missing client credentials")
+		return nil, false
+	}
+
+	if clientSecret == "" {
+		client, err := h.DB.GetClient(ctx, clientID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Invalid client credentials")
+				return nil, false
+			}
+			slog.ErrorContext(ctx, "Failed to get client", slog.String("client_id", clientID), slog.String("err", err.Error()))
+			h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+			return nil, false
+		}
+		if !client.Public {
+			h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Missing client credentials")
+			return nil, false
+		}
+		return client, true
+	}
+
+	client, err := h.DB.GetClientByIDSecret(ctx, clientID, clientSecret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeOAuthError(w, r, http.StatusUnauthorized, oauthErrInvalidClient, "Invalid client credentials")
+			return nil, false
+		}
+		slog.ErrorContext(ctx, "Failed to get client by ID and secret", slog.String("client_id", clientID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func (h *handler) writeTokenResponse(w http.ResponseWriter, r *http.Request, rs tokenResponse) {
+	h.writeOAuthJSON(w, r, http.StatusOK, rs)
+}
+
+func (h *handler) writeOAuthError(w http.ResponseWriter, r *http.Request, status int, code string, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="campfire-auth"`)
+	}
+	h.writeOAuthJSON(w, r, status, oauthError{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+func (h *handler) writeOAuthJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode oauth response", slog.String("err", err.Error()))
+	}
+}
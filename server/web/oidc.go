@@ -0,0 +1,135 @@
+package web
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xjwt"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+	"github.com/topi314/campfire-auth/server/i18n"
+)
+
+type openIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	UILocalesSupported                []string `json:"ui_locales_supported"`
+}
+
+func (h *handler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	issuer := h.issuer()
+
+	h.writeJSON(w, r, openIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/login",
+		TokenEndpoint:                     issuer + "/api/oauth/token",
+		UserInfoEndpoint:                  issuer + "/api/userinfo",
+		IntrospectionEndpoint:             issuer + "/api/oauth/introspect",
+		RevocationEndpoint:                issuer + "/api/oauth/revoke",
+		DeviceAuthorizationEndpoint:       issuer + "/api/oauth/device_authorization",
+		RegistrationEndpoint:              issuer + "/api/oauth/register",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.Cfg.OIDC.SigningAlgorithm},
+		ScopesSupported:                   allScopes(),
+		TokenEndpointAuthMethodsSupported: []string{tokenEndpointAuthMethodBasic, tokenEndpointAuthMethodPost, tokenEndpointAuthMethodNone},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeDeviceCode, grantTypeClientCredentials},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256, codeChallengeMethodPlain},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "preferred_username", "name", "picture"},
+		UILocalesSupported:                i18n.Supported(),
+	})
+}
+
+func (h *handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	keys, err := h.SigningKeys(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get signing keys", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	jwks := xjwt.JWKS{
+		Keys: make([]xjwt.JWK, 0, len(keys)),
+	}
+	for _, key := range keys {
+		jwk, err := xjwt.NewJWK(key.Algorithm, key.ID, key.Key.Public())
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create jwk", slog.String("kid", key.ID), slog.String("err", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	h.writeJSON(w, r, jwks)
+}
+
+type idTokenClaims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Audience          string `json:"aud"`
+	ExpiresAt         int64  `json:"exp"`
+	IssuedAt          int64  `json:"iat"`
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+}
+
+// newIDToken creates a signed ID token for the user of the given login.
+func (h *handler) newIDToken(r *http.Request, login database.Login, user campfire.User, scope string) (string, error) {
+	key, err := h.SigningKey(r.Context())
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := idTokenClaims{
+		Issuer:    h.issuer(),
+		Subject:   user.ID,
+		Audience:  login.ClientID,
+		ExpiresAt: now.Add(time.Duration(h.Cfg.OIDC.IDTokenLifetime)).Unix(),
+		IssuedAt:  now.Unix(),
+		Nonce:     login.Nonce,
+	}
+	if hasScope(scope, scopeProfile) {
+		claims.PreferredUsername = user.Username
+		claims.Name = cmp.Or(user.DisplayName, user.Username)
+		claims.Picture = user.AvatarURL
+	}
+
+	return xjwt.Sign(key.Algorithm, key.ID, key.Key, claims)
+}
+
+func (h *handler) issuer() string {
+	return strings.TrimSuffix(h.Cfg.Server.PublicURL, "/")
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode response", slog.String("err", err.Error()))
+	}
+}
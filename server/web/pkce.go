@@ -0,0 +1,45 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const (
+	codeChallengeMethodS256  = "S256"
+	codeChallengeMethodPlain = "plain"
+)
+
+// codeVerifierRegex matches a valid code verifier or code challenge as defined in RFC 7636 section 4.1.
+var codeVerifierRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+func isValidCodeChallengeMethod(method string) bool {
+	return method == codeChallengeMethodS256 || method == codeChallengeMethodPlain
+}
+
+// verifyCodeChallenge checks whether the code verifier matches the code challenge of the login.
+func verifyCodeChallenge(challenge string, method string, verifier string) bool {
+	if !codeVerifierRegex.MatchString(verifier) {
+		return false
+	}
+
+	var computed string
+	switch method {
+	case codeChallengeMethodS256:
+		computed = s256CodeChallenge(verifier)
+	case codeChallengeMethodPlain:
+		computed = verifier
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// s256CodeChallenge returns the S256 code challenge of the code verifier.
+func s256CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
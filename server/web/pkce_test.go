@@ -0,0 +1,37 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		verifier  string
+		want      bool
+	}{
+		{name: "s256", challenge: s256CodeChallenge(verifier), method: codeChallengeMethodS256, verifier: verifier, want: true},
+		{name: "s256 wrong verifier", challenge: s256CodeChallenge(verifier), method: codeChallengeMethodS256, verifier: strings.Repeat("b", 43), want: false},
+		{name: "s256 rfc 7636 example", challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", method: codeChallengeMethodS256, verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", want: true},
+		{name: "plain", challenge: verifier, method: codeChallengeMethodPlain, verifier: verifier, want: true},
+		{name: "plain wrong verifier", challenge: verifier, method: codeChallengeMethodPlain, verifier: strings.Repeat("b", 43), want: false},
+		{name: "plain challenge with s256 method", challenge: verifier, method: codeChallengeMethodS256, verifier: verifier, want: false},
+		{name: "unknown method", challenge: verifier, method: "S512", verifier: verifier, want: false},
+		{name: "verifier too short", challenge: "short", method: codeChallengeMethodPlain, verifier: "short", want: false},
+		{name: "verifier too long", challenge: strings.Repeat("a", 129), method: codeChallengeMethodPlain, verifier: strings.Repeat("a", 129), want: false},
+		{name: "verifier with invalid characters", challenge: strings.Repeat("a", 42) + "+", method: codeChallengeMethodPlain, verifier: strings.Repeat("a", 42) + "+", want: false},
+		{name: "empty verifier", challenge: s256CodeChallenge(verifier), method: codeChallengeMethodS256, verifier: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeChallenge(tt.challenge, tt.method, tt.verifier); got != tt.want {
+				t.Errorf("verifyCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
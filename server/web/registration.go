@@ -0,0 +1,415 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/topi314/campfire-auth/internal/xpgtype"
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	tokenEndpointAuthMethodBasic = "client_secret_basic"
+	tokenEndpointAuthMethodPost  = "client_secret_post"
+	tokenEndpointAuthMethodNone  = "none"
+
+	registrationErrInvalidRedirectURI    = "invalid_redirect_uri"
+	registrationErrInvalidClientMetadata = "invalid_client_metadata"
+)
+
+// clientMetadata is the client metadata as described in RFC 7591 section 2.
+type clientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	ChannelURL              string   `json:"channel_url,omitempty"`
+}
+
+type clientUpdateRequest struct {
+	clientMetadata
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// clientInformationResponse is the client information response as described in RFC 7591 section 3.2.1.
+type clientInformationResponse struct {
+	clientMetadata
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   *int64 `json:"client_secret_expires_at,omitempty"`
+	RegistrationAccessToken string `json:"registration_access_token"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+}
+
+// RegisterClient implements dynamic client registration as described in RFC 7591.
+func (h *handler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth"`)
+		http.Error(w, "Missing initial access token", http.StatusUnauthorized)
+		return
+	}
+
+	initialAccessToken, err := h.DB.GetInitialAccessToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="invalid_token"`)
+			http.Error(w, "Invalid initial access token", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get initial access token", slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	var metadata clientMetadata
+	if err = json.NewDecoder(r.Body).Decode(&metadata); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, "Invalid JSON body")
+		return
+	}
+
+	public := metadata.TokenEndpointAuthMethod == tokenEndpointAuthMethodNone
+	scopes, code, err := validateClientMetadata(metadata, public)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, code, err.Error())
+		return
+	}
+	channel, err := h.resolveRegistrationChannel(ctx, metadata.ChannelURL)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, err.Error())
+		return
+	}
+
+	var clientSecret string
+	if !public {
+		clientSecret = xrand.RandSecret()
+	}
+	registrationAccessToken := xrand.RandSecret()
+
+	client := database.Client{
+		ID:                      xrand.RandCharCode(),
+		Name:                    metadata.ClientName,
+		Secret:                  clientSecret,
+		RedirectURIs:            xpgtype.NewJSON(metadata.RedirectURIs),
+		Public:                  public,
+		Scopes:                  xpgtype.NewJSON(scopes),
+		RegistrationAccessToken: &registrationAccessToken,
+	}
+	if client.Name == "" {
+		client.Name = "Unnamed Client"
+	}
+
+	if err = h.DB.InsertClientRegistration(ctx, client, channel, initialAccessToken.ID); err != nil {
+		if errors.Is(err, database.ErrInitialAccessTokenUsed) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="invalid_token"`)
+			http.Error(w, "Invalid initial access token", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to insert client registration", slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	var channels []database.ClientChannel
+	if channel != nil {
+		channel.ClientID = client.ID
+		channels = append(channels, *channel)
+	}
+
+	slog.InfoContext(ctx, "Registered client", slog.String("client_id", client.ID), slog.String("initial_access_token", initialAccessToken.Name))
+
+	// fetch the client again to get the creation time
+	registered, err := h.DB.GetClient(ctx, client.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusCreated, h.newClientInformationResponse(*registered, metadata.TokenEndpointAuthMethod, channels))
+}
+
+// GetClientRegistration implements the client read request as described in RFC 7592 section 2.1.
+func (h *handler) GetClientRegistration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	channels, err := h.DB.GetClientChannels(ctx, client.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get client channels", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusOK, h.newClientInformationResponse(*client, "", channels))
+}
+
+// UpdateClientRegistration implements the client update request as described in RFC 7592 section 2.2.
+func (h *handler) UpdateClientRegistration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	var rq clientUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&rq); err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, "Invalid JSON body")
+		return
+	}
+
+	if rq.ClientID != client.ID {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, "client_id does not match")
+		return
+	}
+	if rq.ClientSecret != "" && rq.ClientSecret != client.Secret {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, "client_secret does not match")
+		return
+	}
+	if rq.TokenEndpointAuthMethod != "" && (rq.TokenEndpointAuthMethod == tokenEndpointAuthMethodNone) != client.Public {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, "token_endpoint_auth_method can't be changed")
+		return
+	}
+
+	scopes, code, err := validateClientMetadata(rq.clientMetadata, client.Public)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, code, err.Error())
+		return
+	}
+	channel, err := h.resolveRegistrationChannel(ctx, rq.ChannelURL)
+	if err != nil {
+		h.writeOAuthError(w, r, http.StatusBadRequest, registrationErrInvalidClientMetadata, err.Error())
+		return
+	}
+
+	if rq.ClientName != "" {
+		client.Name = rq.ClientName
+	}
+	client.RedirectURIs = xpgtype.NewJSON(rq.RedirectURIs)
+	client.Scopes = xpgtype.NewJSON(scopes)
+
+	if err = h.DB.UpdateClientRegistration(ctx, *client, channel); err != nil {
+		slog.ErrorContext(ctx, "Failed to update client registration", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	var channels []database.ClientChannel
+	if channel != nil {
+		channel.ClientID = client.ID
+		channels = append(channels, *channel)
+	}
+
+	h.writeOAuthJSON(w, r, http.StatusOK, h.newClientInformationResponse(*client, rq.TokenEndpointAuthMethod, channels))
+}
+
+// DeleteClientRegistration implements the client delete request as described in RFC 7592 section 2.3.
+func (h *handler) DeleteClientRegistration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, ok := h.authenticateRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteClient(ctx, client.ID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete client", slog.String("client_id", client.ID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return
+	}
+
+	slog.InfoContext(ctx, "Deleted client registration", slog.String("client_id", client.ID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticateRegistration looks up the client of a registration access token.
+func (h *handler) authenticateRegistration(w http.ResponseWriter, r *http.Request) (*database.Client, bool) {
+	ctx := r.Context()
+
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth"`)
+		http.Error(w, "Missing registration access token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	clientID := r.PathValue("client_id")
+	client, err := h.DB.GetClientByIDRegistrationAccessToken(ctx, clientID, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="invalid_token"`)
+			http.Error(w, "Invalid registration access token", http.StatusUnauthorized)
+			return nil, false
+		}
+		slog.ErrorContext(ctx, "Failed to get client by registration access token", slog.String("client_id", clientID), slog.String("err", err.Error()))
+		h.writeOAuthError(w, r, http.StatusInternalServerError, oauthErrServerError, "")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// validateClientMetadata validates the metadata of a registration request.
+func validateClientMetadata(metadata clientMetadata, public bool) ([]string, string, error) {
+	if len(metadata.RedirectURIs) == 0 {
+		return nil, registrationErrInvalidRedirectURI, errors.New("redirect_uris is required")
+	}
+	for _, redirectURI := range metadata.RedirectURIs {
+		if err := validateRedirectURI(redirectURI, public); err != nil {
+			return nil, registrationErrInvalidRedirectURI, err
+		}
+	}
+
+	switch metadata.TokenEndpointAuthMethod {
+	case "", tokenEndpointAuthMethodBasic, tokenEndpointAuthMethodPost, tokenEndpointAuthMethodNone:
+	default:
+		return nil, registrationErrInvalidClientMetadata, fmt.Errorf("unsupported token_endpoint_auth_method: %s", metadata.TokenEndpointAuthMethod)
+	}
+
+	grantTypes := clientGrantTypes(public)
+	for _, grantType := range metadata.GrantTypes {
+		if !slices.Contains(grantTypes, grantType) {
+			return nil, registrationErrInvalidClientMetadata, fmt.Errorf("unsupported grant_type: %s", grantType)
+		}
+	}
+
+	for _, responseType := range metadata.ResponseTypes {
+		if responseType != "code" {
+			return nil, registrationErrInvalidClientMetadata, fmt.Errorf("unsupported response_type: %s", responseType)
+		}
+	}
+
+	scopes := supportedScopes()
+	if !public {
+		scopes = allScopes()
+	}
+	if metadata.Scope == "" {
+		return scopes, "", nil
+	}
+
+	requested := strings.Fields(metadata.Scope)
+	for _, scope := range requested {
+		if !slices.Contains(scopes, scope) {
+			return nil, registrationErrInvalidClientMetadata, fmt.Errorf("unsupported scope: %s", scope)
+		}
+	}
+	return requested, "", nil
+}
+
+// validateRedirectURI only allows https redirect URIs.
+func validateRedirectURI(redirectURI string, public bool) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return fmt.Errorf("invalid redirect uri: %s", redirectURI)
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("invalid redirect uri: %s", redirectURI)
+		}
+		return nil
+	case "http":
+		switch u.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+			return nil
+		}
+		return fmt.Errorf("http redirect uris are only allowed for localhost: %s", redirectURI)
+	}
+
+	if !public {
+		return fmt.Errorf("unsupported redirect uri scheme: %s", u.Scheme)
+	}
+	if !strings.Contains(u.Scheme, ".") {
+		return fmt.Errorf("private-use redirect uri schemes have to be in reverse domain name notation: %s", u.Scheme)
+	}
+	return nil
+}
+
+// resolveRegistrationChannel resolves the channel_url of a registration request.
+func (h *handler) resolveRegistrationChannel(ctx context.Context, channelURL string) (*database.ClientChannel, error) {
+	if channelURL == "" {
+		return nil, nil
+	}
+
+	clubID, channelID, err := campfire.ResolveClubAndChannelID(channelURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel_url: %w", err)
+	}
+	if err = h.CheckChannelReadable(ctx, channelID); err != nil {
+		slog.WarnContext(ctx, "Registration channel can't be read", slog.String("channel_id", channelID), slog.String("err", err.Error()))
+		return nil, errors.New("the channel of channel_url can't be read by campfire-auth")
+	}
+
+	return &database.ClientChannel{
+		ClubID:    clubID,
+		ChannelID: channelID,
+		Default:   true,
+	}, nil
+}
+
+// clientGrantTypes returns the grant types a client can use.
+func clientGrantTypes(public bool) []string {
+	if public {
+		return []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeDeviceCode}
+	}
+	return []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeDeviceCode, grantTypeClientCredentials}
+}
+
+// newClientInformationResponse builds the client information response of a registered client.
+func (h *handler) newClientInformationResponse(client database.Client, authMethod string, channels []database.ClientChannel) clientInformationResponse {
+	if client.Public {
+		authMethod = tokenEndpointAuthMethodNone
+	} else if authMethod == "" {
+		authMethod = tokenEndpointAuthMethodBasic
+	}
+
+	rs := clientInformationResponse{
+		clientMetadata: clientMetadata{
+			RedirectURIs:            client.RedirectURIs.V,
+			ClientName:              client.Name,
+			TokenEndpointAuthMethod: authMethod,
+			GrantTypes:              clientGrantTypes(client.Public),
+			ResponseTypes:           []string{"code"},
+			Scope:                   strings.Join(client.Scopes.V, " "),
+		},
+		ClientID:              client.ID,
+		ClientIDIssuedAt:      client.CreatedAt.Unix(),
+		RegistrationClientURI: h.issuer() + "/api/oauth/register/" + client.ID,
+	}
+	if len(channels) > 0 && channels[0].Default {
+		rs.ChannelURL = getChannelLink(channels[0].ClubID, channels[0].ChannelID)
+	}
+	if client.RegistrationAccessToken != nil {
+		rs.RegistrationAccessToken = *client.RegistrationAccessToken
+	}
+	if !client.Public {
+		// client secrets don't expire
+		var expiresAt int64
+		rs.ClientSecret = client.Secret
+		rs.ClientSecretExpiresAt = &expiresAt
+	}
+
+	return rs
+}
@@ -0,0 +1,35 @@
+package web
+
+import "testing"
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		name        string
+		redirectURI string
+		public      bool
+		wantErr     bool
+	}{
+		{name: "https", redirectURI: "https://example.com/callback"},
+		{name: "https with port and query", redirectURI: "https://example.com:8443/callback?a=b"},
+		{name: "http localhost", redirectURI: "http://localhost:8080/callback"},
+		{name: "http loopback ipv4", redirectURI: "http://127.0.0.1:8080/callback"},
+		{name: "http loopback ipv6", redirectURI: "http://[::1]:8080/callback"},
+		{name: "http remote host", redirectURI: "http://example.com/callback", wantErr: true},
+		{name: "relative", redirectURI: "/callback", wantErr: true},
+		{name: "fragment", redirectURI: "https://example.com/callback#fragment", wantErr: true},
+		{name: "https without host", redirectURI: "https:/callback", wantErr: true},
+		{name: "javascript", redirectURI: "javascript:alert(1)", wantErr: true},
+		{name: "javascript public", redirectURI: "javascript:alert(1)", public: true, wantErr: true},
+		{name: "data public", redirectURI: "data:text/html,<script>alert(1)</script>", public: true, wantErr: true},
+		{name: "private-use scheme public", redirectURI: "com.example.app:/callback", public: true},
+		{name: "private-use scheme confidential", redirectURI: "com.example.app:/callback", wantErr: true},
+		{name: "private-use scheme without domain", redirectURI: "myapp://callback", public: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRedirectURI(tt.redirectURI, tt.public); (err != nil) != tt.wantErr {
+				t.Errorf("validateRedirectURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -24,20 +24,63 @@ func Routes(srv *server.Server) http.Handler {
 
 	mux.HandleFunc("GET /admin", h.Admin)
 	mux.HandleFunc("POST /admin/tokens", h.AdminTokens)
+	mux.HandleFunc("POST /admin/channel_url", h.AdminChannelURL)
+	mux.HandleFunc("POST /admin/initial_access_tokens", h.AdminInitialAccessTokens)
+	mux.HandleFunc("POST /admin/initial_access_tokens/{id}/delete", h.AdminDeleteInitialAccessToken)
 	mux.HandleFunc("POST /admin/clients", h.AdminClients)
+	mux.HandleFunc("GET /admin/clients/{client_id}", h.AdminClient)
+	mux.HandleFunc("POST /admin/clients/{client_id}", h.AdminUpdateClient)
+	mux.HandleFunc("POST /admin/clients/{client_id}/channels", h.AdminClientChannels)
+	mux.HandleFunc("POST /admin/clients/{client_id}/channels/default", h.AdminDefaultClientChannel)
+	mux.HandleFunc("POST /admin/clients/{client_id}/channels/delete", h.AdminDeleteClientChannel)
+	mux.HandleFunc("POST /admin/clients/{client_id}/logo", h.AdminUpdateClientLogo)
+	mux.HandleFunc("POST /admin/clients/{client_id}/logo/delete", h.AdminDeleteClientLogo)
+	mux.HandleFunc("POST /admin/clients/{client_id}/saml", h.AdminUpdateClientSAML)
 
 	mux.HandleFunc("GET /login", h.Login)
 	mux.HandleFunc("GET /login/code", h.LoginCode)
 	mux.Handle("GET /login/code/{code}", middlewares.Cache(http.HandlerFunc(h.LoginQRCode)))
 	mux.HandleFunc("GET /login/re/{code}", h.LoginRe)
 	mux.HandleFunc("GET /login/check", h.LoginCheck)
+	mux.HandleFunc("GET /login/events", h.LoginEvents)
+	mux.HandleFunc("POST /login/consent", h.LoginConsent)
+	mux.HandleFunc("POST /login/continue", h.LoginContinue)
+	mux.HandleFunc("POST /login/mention", h.LoginMention)
+	mux.HandleFunc("POST /login/mention/confirm", h.LoginMentionConfirm)
+
+	mux.Handle("GET /clients/{client_id}/logo", middlewares.Cache(http.HandlerFunc(h.ClientLogo)))
+
+	mux.HandleFunc("GET /account", h.Account)
+	mux.HandleFunc("GET /account/callback", h.AccountCallback)
+	mux.HandleFunc("POST /account/grants/{client_id}/revoke", h.AccountRevokeGrant)
+	mux.HandleFunc("POST /account/logout", h.AccountLogout)
+
+	mux.HandleFunc("GET /saml/sso", h.SAMLSSO)
+	mux.HandleFunc("POST /saml/sso", h.SAMLSSO)
 
 	mux.HandleFunc("GET /api/exchange", h.ExchangeCode)
+	mux.HandleFunc("POST /api/oauth/token", h.Token)
+	mux.HandleFunc("POST /api/oauth/device_authorization", h.DeviceAuthorization)
+	mux.HandleFunc("POST /api/oauth/introspect", h.Introspect)
+	mux.HandleFunc("POST /api/oauth/revoke", h.Revoke)
+	mux.HandleFunc("POST /api/oauth/register", h.RegisterClient)
+	mux.HandleFunc("GET /api/oauth/register/{client_id}", h.GetClientRegistration)
+	mux.HandleFunc("PUT /api/oauth/register/{client_id}", h.UpdateClientRegistration)
+	mux.HandleFunc("DELETE /api/oauth/register/{client_id}", h.DeleteClientRegistration)
+	mux.HandleFunc("POST /api/logins", h.CreateLogin)
+	mux.HandleFunc("GET /api/logins/{id}", h.GetLogin)
+	mux.HandleFunc("DELETE /api/logins/{id}", h.DeleteLogin)
+	mux.HandleFunc("GET /api/userinfo", h.UserInfo)
+	mux.HandleFunc("POST /api/userinfo", h.UserInfo)
 	mux.HandleFunc("GET /api/users/search", h.SearchUser)
 	mux.HandleFunc("GET /api/users/{user_id}", h.GetUser)
 
 	mux.HandleFunc("GET /api/docs", h.APIDocs)
 
+	mux.HandleFunc("GET /.well-known/openid-configuration", h.OpenIDConfiguration)
+	mux.HandleFunc("GET /.well-known/jwks.json", h.JWKS)
+	mux.HandleFunc("GET /.well-known/saml-metadata.xml", h.SAMLMetadata)
+
 	mux.Handle("/static/", fileserver)
 
 	if srv.Cfg.Dev {
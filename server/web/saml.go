@@ -0,0 +1,381 @@
+package web
+
+import (
+	"bytes"
+	"compress/flate"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	samlNamespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlNamespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlNamespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	samlBindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlBindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+
+	samlNameIDFormatPersistent = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	samlAttributeNameFormat    = "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"
+	samlStatusSuccess          = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	// samlAssertionLifetime is how long a service provider accepts an assertion after it was issued.
+	samlAssertionLifetime = 5 * time.Minute
+)
+
+type samlAuthnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+}
+
+type samlEntityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor *struct {
+		AssertionConsumerServices []struct {
+			Binding   string `xml:"Binding,attr"`
+			Location  string `xml:"Location,attr"`
+			IsDefault bool   `xml:"isDefault,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata AssertionConsumerService"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SPSSODescriptor"`
+}
+
+// parseSAMLServiceProviderMetadata returns the entity ID and the assertion consumer service URL of the SP.
+func parseSAMLServiceProviderMetadata(metadata string) (string, string, error) {
+	var descriptor samlEntityDescriptor
+	if err := xml.Unmarshal([]byte(metadata), &descriptor); err != nil {
+		return "", "", fmt.Errorf("invalid metadata: %w", err)
+	}
+	if descriptor.EntityID == "" {
+		return "", "", errors.New("missing entityID")
+	}
+	if descriptor.SPSSODescriptor == nil {
+		return "", "", errors.New("missing SPSSODescriptor")
+	}
+
+	var acsURL string
+	for _, acs := range descriptor.SPSSODescriptor.AssertionConsumerServices {
+		if acs.Binding != samlBindingHTTPPost {
+			continue
+		}
+		if acsURL == "" || acs.IsDefault {
+			acsURL = acs.Location
+		}
+	}
+	if acsURL == "" {
+		return "", "", errors.New("missing AssertionConsumerService with HTTP-POST binding")
+	}
+
+	return descriptor.EntityID, acsURL, nil
+}
+
+func (h *handler) samlEntityID() string {
+	return h.issuer() + "/.well-known/saml-metadata.xml"
+}
+
+// SAMLMetadata serves the SAML IdP metadata including the certificate assertions are signed with.
+func (h *handler) SAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	key, err := h.SAMLKey(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get saml key", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+
+	entityDescriptor := doc.CreateElement("md:EntityDescriptor")
+	entityDescriptor.CreateAttr("xmlns:md", samlNamespaceMetadata)
+	entityDescriptor.CreateAttr("xmlns:ds", dsig.Namespace)
+	entityDescriptor.CreateAttr("entityID", h.samlEntityID())
+
+	idpDescriptor := entityDescriptor.CreateElement("md:IDPSSODescriptor")
+	idpDescriptor.CreateAttr("WantAuthnRequestsSigned", "false")
+	idpDescriptor.CreateAttr("protocolSupportEnumeration", samlNamespaceProtocol)
+
+	keyDescriptor := idpDescriptor.CreateElement("md:KeyDescriptor")
+	keyDescriptor.CreateAttr("use", "signing")
+	keyDescriptor.CreateElement("ds:KeyInfo").
+		CreateElement("ds:X509Data").
+		CreateElement("ds:X509Certificate").
+		SetText(base64.StdEncoding.EncodeToString(key.Certificate))
+
+	idpDescriptor.CreateElement("md:NameIDFormat").SetText(samlNameIDFormatPersistent)
+	for _, binding := range []string{samlBindingHTTPRedirect, samlBindingHTTPPost} {
+		sso := idpDescriptor.CreateElement("md:SingleSignOnService")
+		sso.CreateAttr("Binding", binding)
+		sso.CreateAttr("Location", h.issuer()+"/saml/sso")
+	}
+
+	doc.Indent(2)
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	if _, err = doc.WriteTo(w); err != nil {
+		slog.ErrorContext(ctx, "Failed to write saml metadata", slog.String("err", err.Error()))
+	}
+}
+
+// SAMLSSO starts the login of the client a SAML service provider is registered for.
+func (h *handler) SAMLSSO(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	samlRequest := r.Form.Get("SAMLRequest")
+	if samlRequest == "" {
+		http.Error(w, "Missing SAMLRequest", http.StatusBadRequest)
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(samlRequest)
+	if err != nil {
+		http.Error(w, "Invalid SAMLRequest encoding", http.StatusBadRequest)
+		return
+	}
+
+	// the HTTP-Redirect binding additionally deflates the request
+	if r.Method == http.MethodGet {
+		if data, err = io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), 1<<20)); err != nil {
+			http.Error(w, "Invalid SAMLRequest encoding", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var authnRequest samlAuthnRequest
+	if err = xml.Unmarshal(data, &authnRequest); err != nil {
+		http.Error(w, "Invalid AuthnRequest", http.StatusBadRequest)
+		return
+	}
+	if authnRequest.ID == "" || authnRequest.Version != "2.0" {
+		http.Error(w, "Invalid AuthnRequest", http.StatusBadRequest)
+		return
+	}
+	if authnRequest.ProtocolBinding != "" && authnRequest.ProtocolBinding != samlBindingHTTPPost {
+		http.Error(w, "Unsupported ProtocolBinding", http.StatusBadRequest)
+		return
+	}
+
+	sp, err := h.DB.GetSAMLServiceProviderByEntityID(ctx, authnRequest.Issuer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Unknown service provider", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to get saml service provider", slog.String("entity_id", authnRequest.Issuer), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if authnRequest.AssertionConsumerServiceURL != "" && authnRequest.AssertionConsumerServiceURL != sp.ACSURL {
+		http.Error(w, "Invalid AssertionConsumerServiceURL", http.StatusBadRequest)
+		return
+	}
+
+	q := url.Values{}
+	q.Set("client_id", sp.ClientID)
+	q.Set("redirect_uri", sp.ACSURL)
+	// without a channel the login uses the default channel of the client
+	if sp.ClubID != "" && sp.ChannelID != "" {
+		q.Set("club_id", sp.ClubID)
+		q.Set("channel_id", sp.ChannelID)
+	}
+	q.Set("state", r.Form.Get("RelayState"))
+	q.Set("scope", scopeProfile)
+	q.Set("saml_request_id", authnRequest.ID)
+
+	http.Redirect(w, r, "/login?"+q.Encode(), http.StatusFound)
+}
+
+// checkLoginRedirectURI checks whether the redirect URI of a login is registered for the client.
+func (h *handler) checkLoginRedirectURI(r *http.Request, client database.Client, redirectURI string, samlRequestID string) (bool, error) {
+	if samlRequestID == "" {
+		return slices.Contains(client.RedirectURIs.V, redirectURI), nil
+	}
+
+	sp, err := h.DB.GetSAMLServiceProvider(r.Context(), client.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return sp.ACSURL == redirectURI, nil
+}
+
+type SAMLPostVars struct {
+	ACSURL       string
+	SAMLResponse string
+	RelayState   string
+}
+
+// writeSAMLResponse posts a signed assertion to the assertion consumer service.
+func (h *handler) writeSAMLResponse(w http.ResponseWriter, r *http.Request, login database.Login, scope string) {
+	ctx := r.Context()
+
+	sp, err := h.DB.GetSAMLServiceProvider(ctx, login.ClientID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get saml service provider", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if sp.ACSURL != login.RedirectURI {
+		http.Error(w, "SAML service provider changed during login", http.StatusBadRequest)
+		return
+	}
+
+	var user campfire.User
+	if err = json.Unmarshal(*login.User, &user); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal login user", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.DB.UpsertGrant(ctx, login.ClientID, user.ID, scope); err != nil {
+		slog.ErrorContext(ctx, "Failed to upsert grant", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := h.newSAMLResponse(r, *sp, *login.SAMLRequestID, newScopedUser(user, scope))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create saml response", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.DB.DeleteSAMLLogin(ctx, login.ID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete saml login", slog.String("client_id", login.ClientID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.Templates().ExecuteTemplate(w, "saml_post.gohtml", SAMLPostVars{
+		ACSURL:       sp.ACSURL,
+		SAMLResponse: base64.StdEncoding.EncodeToString(response),
+		RelayState:   login.State,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to render saml post template", slog.String("err", err.Error()))
+	}
+}
+
+// newSAMLResponse creates a SAML response with a signed assertion for the given user.
+func (h *handler) newSAMLResponse(r *http.Request, sp database.SAMLServiceProvider, requestID string, user ScopedUser) ([]byte, error) {
+	key, err := h.SAMLKey(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	issueInstant := now.Format(time.RFC3339)
+	notOnOrAfter := now.Add(samlAssertionLifetime).Format(time.RFC3339)
+	assertionID := "_" + xrand.RandCharCode()
+
+	assertion := etree.NewElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", samlNamespaceAssertion)
+	assertion.CreateAttr("xmlns:xs", "http://www.w3.org/2001/XMLSchema")
+	assertion.CreateAttr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	assertion.CreateAttr("ID", assertionID)
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", issueInstant)
+	assertion.CreateElement("saml:Issuer").SetText(h.samlEntityID())
+
+	subject := assertion.CreateElement("saml:Subject")
+	nameID := subject.CreateElement("saml:NameID")
+	nameID.CreateAttr("Format", samlNameIDFormatPersistent)
+	nameID.SetText(user.ID)
+	subjectConfirmation := subject.CreateElement("saml:SubjectConfirmation")
+	subjectConfirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
+	subjectConfirmationData := subjectConfirmation.CreateElement("saml:SubjectConfirmationData")
+	subjectConfirmationData.CreateAttr("InResponseTo", requestID)
+	subjectConfirmationData.CreateAttr("NotOnOrAfter", notOnOrAfter)
+	subjectConfirmationData.CreateAttr("Recipient", sp.ACSURL)
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Add(-time.Minute).Format(time.RFC3339))
+	conditions.CreateAttr("NotOnOrAfter", notOnOrAfter)
+	conditions.CreateElement("saml:AudienceRestriction").
+		CreateElement("saml:Audience").
+		SetText(sp.EntityID)
+
+	authnStatement := assertion.CreateElement("saml:AuthnStatement")
+	authnStatement.CreateAttr("AuthnInstant", issueInstant)
+	authnStatement.CreateAttr("SessionIndex", assertionID)
+	authnStatement.CreateElement("saml:AuthnContext").
+		CreateElement("saml:AuthnContextClassRef").
+		SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:unspecified")
+
+	attributes := map[string]*string{
+		"id":          &user.ID,
+		"username":    user.Username,
+		"displayName": user.DisplayName,
+	}
+	attributeStatement := assertion.CreateElement("saml:AttributeStatement")
+	for _, name := range []string{"id", "username", "displayName"} {
+		value := attributes[name]
+		if value == nil {
+			continue
+		}
+		attribute := attributeStatement.CreateElement("saml:Attribute")
+		attribute.CreateAttr("Name", name)
+		attribute.CreateAttr("NameFormat", samlAttributeNameFormat)
+		attributeValue := attribute.CreateElement("saml:AttributeValue")
+		attributeValue.CreateAttr("xsi:type", "xs:string")
+		attributeValue.SetText(*value)
+	}
+
+	signingContext, err := dsig.NewSigningContext(key.Key, [][]byte{key.Certificate})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing context: %w", err)
+	}
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	signature, err := signingContext.ConstructSignature(assertion, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign assertion: %w", err)
+	}
+	// the schema requires the signature to directly follow the issuer
+	assertion.InsertChildAt(1, signature)
+
+	doc := etree.NewDocument()
+	response := doc.CreateElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", samlNamespaceProtocol)
+	response.CreateAttr("xmlns:saml", samlNamespaceAssertion)
+	response.CreateAttr("ID", "_"+xrand.RandCharCode())
+	response.CreateAttr("Version", "2.0")
+	response.CreateAttr("IssueInstant", issueInstant)
+	response.CreateAttr("Destination", sp.ACSURL)
+	response.CreateAttr("InResponseTo", requestID)
+	response.CreateElement("saml:Issuer").SetText(h.samlEntityID())
+	response.CreateElement("samlp:Status").
+		CreateElement("samlp:StatusCode").
+		CreateAttr("Value", samlStatusSuccess)
+	response.AddChild(assertion)
+
+	return doc.WriteToBytes()
+}
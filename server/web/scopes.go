@@ -0,0 +1,178 @@
+package web
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const (
+	scopeOpenID       = "openid"
+	scopeProfile      = "profile"
+	scopeBadges       = "badges"
+	scopeGameProfiles = "game_profiles"
+
+	scopeUsersRead = "users:read"
+)
+
+// clientScopes are the scopes a client can request for itself using the client_credentials grant.
+var clientScopes = []string{
+	scopeUsersRead,
+}
+
+// userScopes are the scopes a user can grant to a client.
+var userScopes = []Scope{
+	{Name: scopeOpenID, Description: "Confirm your Campfire identity", Required: true},
+	{Name: scopeProfile, Description: "Your username, display name and avatar"},
+	{Name: scopeBadges, Description: "Your Campfire badges"},
+	{Name: scopeGameProfiles, Description: "Your game profiles including codename, level and team"},
+}
+
+type Scope struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+func supportedScopes() []string {
+	scopes := make([]string, 0, len(userScopes))
+	for _, scope := range userScopes {
+		scopes = append(scopes, scope.Name)
+	}
+	return scopes
+}
+
+// allScopes returns all user and client scopes.
+func allScopes() []string {
+	return append(supportedScopes(), clientScopes...)
+}
+
+func hasScope(scope string, s string) bool {
+	return slices.Contains(strings.Fields(scope), s)
+}
+
+// scopeError is returned for a requested scope which is unsupported or not allowed for the client.
+type scopeError struct {
+	Scope      string
+	NotAllowed bool
+}
+
+func (e scopeError) Error() string {
+	if e.NotAllowed {
+		return "scope not allowed for this client: " + e.Scope
+	}
+	return "unsupported scope: " + e.Scope
+}
+
+// resolveScope validates the requested scope against the scopes the client is allowed to request.
+func resolveScope(client database.Client, scope string) (string, error) {
+	if scope == "" {
+		var scopes []string
+		for _, s := range client.Scopes.V {
+			if s != scopeOpenID && slices.Contains(supportedScopes(), s) {
+				scopes = append(scopes, s)
+			}
+		}
+		return strings.Join(scopes, " "), nil
+	}
+
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(supportedScopes(), s) {
+			return "", scopeError{Scope: s}
+		}
+		if !slices.Contains(client.Scopes.V, s) {
+			return "", scopeError{Scope: s, NotAllowed: true}
+		}
+	}
+	return strings.Join(strings.Fields(scope), " "), nil
+}
+
+// resolveClientScope validates the requested scope of a client_credentials grant.
+func resolveClientScope(client database.Client, scope string) (string, error) {
+	if scope == "" {
+		var scopes []string
+		for _, s := range client.Scopes.V {
+			if slices.Contains(clientScopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+		if len(scopes) == 0 {
+			return "", errors.New("client is not allowed to request any client scopes")
+		}
+		return strings.Join(scopes, " "), nil
+	}
+
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(clientScopes, s) {
+			return "", scopeError{Scope: s}
+		}
+		if !slices.Contains(client.Scopes.V, s) {
+			return "", scopeError{Scope: s, NotAllowed: true}
+		}
+	}
+	return strings.Join(strings.Fields(scope), " "), nil
+}
+
+// requestedScopes returns the consent screen entries for the requested scope.
+func requestedScopes(scope string, required ...string) []Scope {
+	var scopes []Scope
+	for _, s := range userScopes {
+		if hasScope(scope, s.Name) {
+			if slices.Contains(required, s.Name) {
+				s.Required = true
+			}
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// loginRequiredScopes returns the scopes a login needs in addition to the always required ones.
+func loginRequiredScopes(login database.Login) []string {
+	if login.SAMLRequestID != nil {
+		return []string{scopeProfile}
+	}
+	return nil
+}
+
+// grantScope returns the scope the user granted out of the requested scope.
+func grantScope(requested string, granted []string, required ...string) string {
+	var scopes []string
+	for _, s := range requestedScopes(requested, required...) {
+		if s.Required || slices.Contains(granted, s.Name) {
+			scopes = append(scopes, s.Name)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// ScopedUser is a campfire.User which only contains the fields released by a scope.
+type ScopedUser struct {
+	ID           string                  `json:"id"`
+	Username     *string                 `json:"username,omitempty"`
+	DisplayName  *string                 `json:"displayName,omitempty"`
+	AvatarURL    *string                 `json:"avatarUrl,omitempty"`
+	Badges       *[]campfire.Badge       `json:"badges,omitempty"`
+	GameProfiles *[]campfire.GameProfile `json:"gameProfiles,omitempty"`
+}
+
+func newScopedUser(user campfire.User, scope string) ScopedUser {
+	scopedUser := ScopedUser{
+		ID: user.ID,
+	}
+	if hasScope(scope, scopeProfile) {
+		scopedUser.Username = &user.Username
+		scopedUser.DisplayName = &user.DisplayName
+		scopedUser.AvatarURL = &user.AvatarURL
+	}
+	if hasScope(scope, scopeBadges) {
+		scopedUser.Badges = &user.Badges
+	}
+	if hasScope(scope, scopeGameProfiles) {
+		scopedUser.GameProfiles = &user.GameProfiles
+	}
+	return scopedUser
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/topi314/campfire-auth/internal/xpgtype"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+func TestResolveScope(t *testing.T) {
+	client := database.Client{
+		Scopes: xpgtype.JSON[[]string]{V: []string{scopeOpenID, scopeProfile, scopeBadges, scopeUsersRead}},
+	}
+
+	tests := []struct {
+		name    string
+		scope   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty scope defaults to allowed user scopes", scope: "", want: "profile badges"},
+		{name: "single scope", scope: "openid", want: "openid"},
+		{name: "multiple scopes", scope: "openid profile badges", want: "openid profile badges"},
+		{name: "extra whitespace", scope: "  openid \t profile  ", want: "openid profile"},
+		{name: "unsupported scope", scope: "openid email", wantErr: true},
+		{name: "scope not allowed for client", scope: "openid game_profiles", wantErr: true},
+		{name: "client scope", scope: "users:read", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveScope(client, tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveScope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		granted   []string
+		required  []string
+		want      string
+	}{
+		{name: "all granted", requested: "openid profile badges", granted: []string{"profile", "badges"}, want: "openid profile badges"},
+		{name: "openid is always granted", requested: "openid profile", granted: nil, want: "openid"},
+		{name: "unticked scope", requested: "openid profile badges", granted: []string{"badges"}, want: "openid badges"},
+		{name: "granted scope which was not requested", requested: "openid", granted: []string{"profile"}, want: "openid"},
+		{name: "additional required scope", requested: "profile badges", granted: nil, required: []string{scopeProfile}, want: "profile"},
+		{name: "additional required scope which was not requested", requested: "badges", granted: nil, required: []string{scopeProfile}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grantScope(tt.requested, tt.granted, tt.required...); got != tt.want {
+				t.Errorf("grantScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/topi314/campfire-auth/internal/xrand"
+	"github.com/topi314/campfire-auth/server/campfire"
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+const sessionCookieName = "campfire_auth_session"
+
+// getSession returns the session of the signed session cookie.
+func (h *handler) getSession(r *http.Request) (*database.Session, error) {
+	if !h.Cfg.Session.Enabled {
+		return nil, nil
+	}
+
+	token, ok := h.sessionCookieToken(r)
+	if !ok {
+		return nil, nil
+	}
+
+	session, err := h.DB.GetSession(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// createSession starts a new session for the user of the verified login.
+func (h *handler) createSession(w http.ResponseWriter, r *http.Request, login database.Login) error {
+	ctx := r.Context()
+
+	if login.User == nil {
+		return errors.New("login is not verified")
+	}
+
+	var user campfire.User
+	if err := json.Unmarshal(*login.User, &user); err != nil {
+		return fmt.Errorf("failed to unmarshal login user: %w", err)
+	}
+
+	if oldToken, ok := h.sessionCookieToken(r); ok {
+		if err := h.DB.DeleteSession(ctx, oldToken); err != nil {
+			return err
+		}
+	}
+
+	expiresAt := time.Now().UTC().Add(time.Duration(h.Cfg.Session.Lifetime))
+	session := database.Session{
+		Token:     xrand.RandSecret(),
+		UserID:    user.ID,
+		User:      *login.User,
+		ExpiresAt: expiresAt,
+	}
+	if err := h.DB.InsertSession(ctx, session); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    session.Token + "." + h.signSessionToken(session.Token),
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   strings.HasPrefix(h.Cfg.Server.PublicURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// sessionCookieToken returns the session token of the session cookie if its signature is valid.
+func (h *handler) sessionCookieToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", false
+	}
+
+	token, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || token == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(h.signSessionToken(token))) {
+		return "", false
+	}
+
+	return token, true
+}
+
+func (h *handler) signSessionToken(token string) string {
+	mac := hmac.New(sha256.New, []byte(h.Cfg.Session.Secret))
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionLoginUser returns the verified user of a login continued from the session.
+func (h *handler) sessionLoginUser(ctx context.Context, session database.Session, client database.Client, clubID string) (*database.LoginUser, error) {
+	loginUser := database.LoginUser{
+		User: session.User,
+	}
+
+	member, err := h.GetLoginClubMember(ctx, client, clubID, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		memberData, err := json.Marshal(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal club member: %w", err)
+		}
+		rawMemberData := json.RawMessage(memberData)
+		loginUser.ClubMember = &rawMemberData
+	}
+
+	return &loginUser, nil
+}
+
+func newSessionUser(session database.Session) (*User, error) {
+	var user campfire.User
+	if err := json.Unmarshal(session.User, &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session user: %w", err)
+	}
+
+	return &User{
+		ID:          user.ID,
+		DisplayName: cmp.Or(user.DisplayName, user.Username),
+		Username:    user.Username,
+		AvatarURL:   cmp.Or(user.AvatarURL, "/static/default_avatar.png"),
+	}, nil
+}
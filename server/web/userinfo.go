@@ -0,0 +1,78 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/topi314/campfire-auth/server/database"
+)
+
+type userInfoResponse struct {
+	ScopedUser
+	Subject string `json:"sub"`
+}
+
+func (h *handler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accessToken, ok := h.authenticateBearer(w, r)
+	if !ok {
+		return
+	}
+
+	// client access tokens are not issued for a user
+	if accessToken.UserID == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="invalid_token"`)
+		http.Error(w, "Access token is not issued for a user", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.Campfire.GetUserByID(ctx, accessToken.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user by ID", slog.String("user_id", accessToken.UserID), slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, r, userInfoResponse{
+		ScopedUser: newScopedUser(*user, accessToken.Scope),
+		Subject:    user.ID,
+	})
+}
+
+// authenticateBearer looks up the bearer access token of the request.
+func (h *handler) authenticateBearer(w http.ResponseWriter, r *http.Request) (*database.AccessToken, bool) {
+	ctx := r.Context()
+
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth"`)
+		http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	accessToken, err := h.DB.GetAccessToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="campfire-auth", error="invalid_token"`)
+			http.Error(w, "Invalid bearer token", http.StatusUnauthorized)
+			return nil, false
+		}
+		slog.ErrorContext(ctx, "Failed to get access token", slog.String("err", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return accessToken, true
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}